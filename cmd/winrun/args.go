@@ -0,0 +1,92 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/sibikrish3000/gowinbridge/pkg/bridge"
+)
+
+// argIndexes collects repeatable argument index flags such as --path-arg N.
+type argIndexes []int
+
+func (a *argIndexes) String() string {
+	s := make([]string, len(*a))
+	for i, n := range *a {
+		s[i] = strconv.Itoa(n)
+	}
+	return strings.Join(s, ", ")
+}
+
+func (a *argIndexes) Set(val string) error {
+	n, err := strconv.Atoi(val)
+	if err != nil || n < 1 {
+		return fmt.Errorf("invalid argument index %q, expected 1 or more", val)
+	}
+	*a = append(*a, n)
+	return nil
+}
+
+// argPolicyFlags holds the per-argument translation flags. Indexes count
+// the arguments after the command from 1, like $1 in a shell script.
+type argPolicyFlags struct {
+	path     argIndexes
+	pathList argIndexes
+	literal  argIndexes
+}
+
+// register defines --path-arg, --path-list-arg and --no-path-arg on fs.
+func (f *argPolicyFlags) register(fs *flag.FlagSet) {
+	fs.Var(&f.path, "path-arg", "Always translate argument N as a path (repeatable)")
+	fs.Var(&f.pathList, "path-list-arg", "Translate argument N as a colon-separated path list (repeatable)")
+	fs.Var(&f.literal, "no-path-arg", "Never translate argument N (repeatable)")
+}
+
+// policies returns the bridge.CommandConfig.ArgPolicies for nargs
+// arguments. Indexes past the last argument are ignored, so that a shim
+// can name arguments its callers may leave out.
+func (f *argPolicyFlags) policies(nargs int) ([]bridge.ArgPolicy, error) {
+	if len(f.path)+len(f.pathList)+len(f.literal) == 0 {
+		return nil, nil
+	}
+	policies := make([]bridge.ArgPolicy, nargs)
+	for _, set := range []struct {
+		indexes argIndexes
+		policy  bridge.ArgPolicy
+	}{
+		{f.path, bridge.ArgPath},
+		{f.pathList, bridge.ArgPathList},
+		{f.literal, bridge.ArgLiteral},
+	} {
+		for _, n := range set.indexes {
+			if n > nargs {
+				continue
+			}
+			if prev := policies[n-1]; prev != bridge.ArgAuto && prev != set.policy {
+				return nil, fmt.Errorf("argument %d is marked both %s and %s", n, prev, set.policy)
+			}
+			policies[n-1] = set.policy
+		}
+	}
+	return policies, nil
+}
+
+// args returns the flags as winrun arguments, for a shim to pass on.
+func (f *argPolicyFlags) args() []string {
+	var args []string
+	for _, set := range []struct {
+		name    string
+		indexes argIndexes
+	}{
+		{"--path-arg", f.path},
+		{"--path-list-arg", f.pathList},
+		{"--no-path-arg", f.literal},
+	} {
+		for _, n := range set.indexes {
+			args = append(args, set.name, strconv.Itoa(n))
+		}
+	}
+	return args
+}
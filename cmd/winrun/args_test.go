@@ -0,0 +1,52 @@
+package main
+
+import (
+	"flag"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/sibikrish3000/gowinbridge/pkg/bridge"
+)
+
+func TestArgPolicyFlags(t *testing.T) {
+	var f argPolicyFlags
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	f.register(fs)
+	if err := fs.Parse([]string{"--path-arg", "2", "--no-path-arg", "1", "--path-list-arg", "3", "--path-arg", "9"}); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := f.policies(4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []bridge.ArgPolicy{bridge.ArgLiteral, bridge.ArgPath, bridge.ArgPathList, bridge.ArgAuto}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("policies(4) = %v, want %v", got, want)
+	}
+
+	wantArgs := []string{"--path-arg", "2", "--path-arg", "9", "--path-list-arg", "3", "--no-path-arg", "1"}
+	if args := f.args(); !reflect.DeepEqual(args, wantArgs) {
+		t.Errorf("args() = %q, want %q", args, wantArgs)
+	}
+}
+
+func TestArgPolicyFlags_Errors(t *testing.T) {
+	var f argPolicyFlags
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	fs.SetOutput(new(strings.Builder))
+	f.register(fs)
+	if err := fs.Parse([]string{"--path-arg", "0"}); err == nil {
+		t.Error("--path-arg 0 accepted")
+	}
+
+	f = argPolicyFlags{path: argIndexes{1}, literal: argIndexes{1}}
+	if _, err := f.policies(1); err == nil || !strings.Contains(err.Error(), "both path and literal") {
+		t.Errorf("conflicting flags: err = %v", err)
+	}
+
+	if got, err := (&argPolicyFlags{}).policies(3); got != nil || err != nil {
+		t.Errorf("no flags: policies = %v, %v; want nil", got, err)
+	}
+}
@@ -6,24 +6,37 @@
 //
 //	winrun [flags] -- <command> [args...]
 //	winrun shim <install|list|remove> [options]
+//	winrun which <name>...
 //
 // Flags:
 //
 //	--concurrency N    Max concurrent executions (default: NumCPU)
 //	--convert-paths    Auto-detect and convert file path arguments
+//	--path-arg N       Always translate argument N as a path (repeatable)
+//	--path-list-arg N  Translate argument N as a colon-separated path list (repeatable)
+//	--no-path-arg N    Never translate argument N (repeatable)
+//	--rewrite-paths    Rewrite Windows paths in output to Linux paths
+//	--unc-host HOST    UNC host of Linux paths: wsl.localhost or wsl$ (default: by Windows build)
 //	--encoding ENC     Output encoding: utf8, cp1252, utf16le, utf16be, auto
+//	--quoting RULE     Argument quoting: auto, none, msvcrt, cmd, powershell
 //	--env KEY=VAL      Set environment variable (repeatable)
 //	--tunnel-env       Enable WSLENV tunneling for --env vars
-//	--interactive      Run in interactive/PTY mode (auto-detected)
+//	--interactive      Run in interactive mode (auto-detected)
+//	--pty              Allocate a pseudo-terminal in interactive mode (implies --interactive)
+//	--combined         Merge stdout and stderr in arrival order (like 2>&1)
+//	--raw              Write output byte-for-byte, without decoding or newline changes
 //	--timeout DURATION Max execution time (e.g., 30s, 5m)
+//	--grace-period DUR Time between Ctrl+C and Windows process tree kill (default 5s)
 //	--version          Print version and exit
 //	--help             Show usage
 package main
 
 import (
 	"context"
+	"errors"
 	"flag"
 	"fmt"
+	"io"
 	"os"
 	"os/signal"
 	"runtime"
@@ -34,6 +47,7 @@ import (
 	"github.com/sibikrish3000/gowinbridge/internal/wsl"
 	"github.com/sibikrish3000/gowinbridge/pkg/bridge"
 	"github.com/sibikrish3000/gowinbridge/pkg/workerpool"
+	"github.com/sibikrish3000/gowinbridge/pkg/wslpath"
 )
 
 // Build-time variables, injected via -ldflags.
@@ -43,6 +57,10 @@ var (
 	date    = "unknown"
 )
 
+// forceExitSlack is how long winrun waits, beyond the grace period, for
+// the Windows process tree to be killed after a signal.
+const forceExitSlack = 20 * time.Second
+
 // envFlags collects repeatable --env KEY=VAL flags.
 type envFlags []string
 
@@ -58,6 +76,10 @@ func main() {
 		handleShim(os.Args[2:])
 		return
 	}
+	if len(os.Args) > 1 && os.Args[1] == "which" {
+		handleWhich(os.Args[2:])
+		return
+	}
 
 	var (
 		concurrency  int
@@ -65,33 +87,56 @@ func main() {
 		envVars      envFlags
 		tunnelEnv    bool
 		timeout      time.Duration
+		gracePeriod  time.Duration
 		showVersion  bool
 		encoding     string
+		quoting      string
 		interactive  bool
+		usePTY       bool
+		raw          bool
+		combined     bool
+		rewritePaths bool
+		uncHost      string
+		argFlags     argPolicyFlags
 	)
 
 	flag.IntVar(&concurrency, "concurrency", runtime.NumCPU(), "Max concurrent executions")
 	flag.BoolVar(&convertPaths, "convert-paths", false, "Auto-convert file path arguments to Windows format")
+	argFlags.register(flag.CommandLine)
 	flag.Var(&envVars, "env", "Set environment variable as KEY=VAL (repeatable)")
 	flag.BoolVar(&tunnelEnv, "tunnel-env", false, "Enable WSLENV tunneling for specified env vars")
 	flag.DurationVar(&timeout, "timeout", 0, "Max execution time (e.g., 30s, 5m)")
+	flag.DurationVar(&gracePeriod, "grace-period", bridge.DefaultGracePeriod, "Time allowed after interrupt/timeout before the Windows process tree is killed")
 	flag.BoolVar(&showVersion, "version", false, "Print version information and exit")
+	flag.BoolVar(&rewritePaths, "rewrite-paths", false, "Rewrite Windows paths in output (C:\\src\\main.c(12)) to Linux paths")
+	flag.StringVar(&uncHost, "unc-host", "", "UNC host of Linux paths: wsl.localhost or wsl$ (default: by Windows build)")
 	flag.StringVar(&encoding, "encoding", "", "Output encoding: utf8, cp1252, utf16le, utf16be, auto")
-	flag.BoolVar(&interactive, "interactive", false, "Run in interactive/PTY mode (bypasses output capture)")
+	flag.StringVar(&quoting, "quoting", "auto", "Argument quoting: auto, none, msvcrt, cmd, powershell")
+	flag.BoolVar(&interactive, "interactive", false, "Run in interactive mode (bypasses output capture)")
+	flag.BoolVar(&usePTY, "pty", false, "Allocate a pseudo-terminal with raw mode and resize forwarding (implies --interactive)")
+	flag.BoolVar(&combined, "combined", false, "Merge stdout and stderr into stdout in arrival order (like 2>&1)")
+	flag.BoolVar(&raw, "raw", false, "Write output byte-for-byte, without decoding or newline changes")
 
 	flag.Usage = func() {
 		fmt.Fprintf(os.Stderr, "Usage: winrun [flags] -- <command> [args...]\n")
-		fmt.Fprintf(os.Stderr, "       winrun shim <install|list|remove> [options]\n\n")
+		fmt.Fprintf(os.Stderr, "       winrun shim <install|list|remove> [options]\n")
+		fmt.Fprintf(os.Stderr, "       winrun which <name>...\n\n")
 		fmt.Fprintf(os.Stderr, "Execute Windows binaries from WSL with path translation and env bridging.\n\n")
 		fmt.Fprintf(os.Stderr, "Flags:\n")
 		flag.PrintDefaults()
 		fmt.Fprintf(os.Stderr, "\nExamples:\n")
 		fmt.Fprintf(os.Stderr, "  winrun -- cmd.exe /c echo hello\n")
 		fmt.Fprintf(os.Stderr, "  winrun --convert-paths -- cmd.exe /c type ./myfile.txt\n")
+		fmt.Fprintf(os.Stderr, "  winrun --convert-paths --no-path-arg 1 -- rg.exe /api/v1/ ./src\n")
+		fmt.Fprintf(os.Stderr, "  winrun --convert-paths --rewrite-paths -- cl.exe /c ./main.c\n")
 		fmt.Fprintf(os.Stderr, "  winrun --encoding cp1252 -- cmd.exe /c chcp\n")
 		fmt.Fprintf(os.Stderr, "  winrun -interactive -- python.exe\n")
+		fmt.Fprintf(os.Stderr, "  winrun --pty -- vim.exe notes.txt\n")
+		fmt.Fprintf(os.Stderr, "  winrun --combined -- msbuild.exe app.sln > build.log\n")
+		fmt.Fprintf(os.Stderr, "  winrun --raw -- certutil.exe -encode in.bin out.b64 > log.txt\n")
 		fmt.Fprintf(os.Stderr, "  winrun --env MY_VAR=hello --tunnel-env -- cmd.exe /c echo %%MY_VAR%%\n")
 		fmt.Fprintf(os.Stderr, "  winrun --concurrency 4 --timeout 30s -- powershell.exe -Command Get-Process\n")
+		fmt.Fprintf(os.Stderr, "  winrun -- ./build.cmd release\n")
 		fmt.Fprintf(os.Stderr, "  winrun shim install docker.exe --as docker\n")
 	}
 
@@ -121,6 +166,10 @@ func main() {
 	}
 	fmt.Fprintf(os.Stderr, "[winrun] WSL%d environment detected\n", wsl.DetectWSLVersion())
 
+	if usePTY {
+		interactive = true
+	}
+
 	// Auto-detect interactive mode if stdin is a terminal.
 	if !interactive && bridge.IsTerminal(int(os.Stdin.Fd())) {
 		// Only auto-enable for known interactive binaries.
@@ -129,6 +178,8 @@ func main() {
 			strings.Contains(cmd, "mysql") || strings.Contains(cmd, "psql") ||
 			strings.Contains(cmd, "irb") || strings.Contains(cmd, "bash") {
 			interactive = true
+			// TUI and readline prompts need a real terminal on both ends.
+			usePTY = bridge.IsTerminal(int(os.Stdout.Fd()))
 			fmt.Fprintln(os.Stderr, "[winrun] Auto-detected interactive mode")
 		}
 	}
@@ -144,19 +195,46 @@ func main() {
 		envMap[parts[0]] = parts[1]
 	}
 
+	quoteRule, err := bridge.ParseQuoteRule(quoting)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	switch uncHost {
+	case "", wslpath.HostLocalhost, wslpath.HostLegacy:
+		wslpath.SetUNCHost(uncHost)
+	default:
+		fmt.Fprintf(os.Stderr, "Error: invalid --unc-host %q, expected %s or %s\n", uncHost, wslpath.HostLocalhost, wslpath.HostLegacy)
+		os.Exit(1)
+	}
+
 	// Build the command config.
 	command := args[0]
 	cmdArgs := args[1:]
 
+	argPolicies, err := argFlags.policies(len(cmdArgs))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+
 	config := bridge.CommandConfig{
-		Command:      command,
-		Args:         cmdArgs,
-		Env:          envMap,
-		EnvTunneling: tunnelEnv,
-		Timeout:      timeout,
-		ConvertPaths: convertPaths,
-		Encoding:     encoding,
-		Interactive:  interactive,
+		Command:            command,
+		Args:               cmdArgs,
+		Env:                envMap,
+		EnvTunneling:       tunnelEnv,
+		Timeout:            timeout,
+		Termination:        bridge.TerminationPolicy{GracePeriod: gracePeriod},
+		ConvertPaths:       convertPaths,
+		ArgPolicies:        argPolicies,
+		Quoting:            quoteRule,
+		Encoding:           encoding,
+		Interactive:        interactive,
+		PTY:                usePTY,
+		RawOutput:          raw,
+		Combined:           combined,
+		RewriteOutputPaths: rewritePaths,
 	}
 
 	// Always make stdin available to the command.
@@ -176,18 +254,23 @@ func main() {
 	sigCh := make(chan os.Signal, 2)
 	signal.Notify(sigCh, syscall.SIGINT, syscall.SIGTERM)
 
+	// The first signal cancels the context, which makes the bridge interrupt
+	// the command and kill its Windows process tree after the grace period.
+	// A second signal, or the tree kill taking too long, exits immediately.
+	forceAfter := gracePeriod + forceExitSlack
 	go func() {
 		sig := <-sigCh
-		fmt.Fprintf(os.Stderr, "\n[winrun] Received %s, requesting graceful shutdown...\n", sig)
-		cancel() // Cancel context → sends SIGTERM to child via exec.CommandContext.
+		fmt.Fprintf(os.Stderr, "\n[winrun] Received %s, interrupting (process tree killed after %s)...\n", sig, gracePeriod)
+		cancel()
 
-		// Wait for a second signal or timeout for force kill.
 		select {
 		case sig2 := <-sigCh:
 			fmt.Fprintf(os.Stderr, "[winrun] Received %s again, force exiting.\n", sig2)
+			bridge.RestoreTerminal()
 			os.Exit(130)
-		case <-time.After(5 * time.Second):
-			fmt.Fprintln(os.Stderr, "[winrun] Grace period expired, force exiting.")
+		case <-time.After(forceAfter):
+			fmt.Fprintln(os.Stderr, "[winrun] Shutdown did not complete in time, force exiting.")
+			bridge.RestoreTerminal()
 			os.Exit(130)
 		}
 	}()
@@ -204,19 +287,26 @@ func main() {
 
 	exitCode := 0
 	for result := range pool.Results() {
-		if result.Err != nil {
+		// Whatever the command wrote before it failed, was timed out or was
+		// canceled is still worth showing.
+		writeOutput(os.Stdout, os.Stderr, result.Output, raw, combined)
+
+		// A non-zero exit still carries the command's output; report it
+		// below. Timeouts and cancellations keep the conventional codes.
+		var exitErr *bridge.ExitError
+		if result.Err != nil && !errors.As(result.Err, &exitErr) {
 			fmt.Fprintf(os.Stderr, "[winrun] Error: %v\n", result.Err)
-			exitCode = 1
+			switch {
+			case errors.Is(result.Err, bridge.ErrTimeout):
+				exitCode = 124
+			case errors.Is(result.Err, bridge.ErrCanceled):
+				exitCode = 130
+			default:
+				exitCode = 1
+			}
 			continue
 		}
 
-		if result.Output.Stdout != "" {
-			fmt.Println(result.Output.Stdout)
-		}
-		if result.Output.Stderr != "" {
-			fmt.Fprintln(os.Stderr, result.Output.Stderr)
-		}
-
 		fmt.Fprintf(os.Stderr, "[winrun] Command %q completed in %s (exit code: %d)\n",
 			result.Config.Command, result.Output.Duration.Round(time.Millisecond), result.Output.ExitCode)
 
@@ -227,3 +317,24 @@ func main() {
 
 	os.Exit(exitCode)
 }
+
+// writeOutput prints the captured output of a command: the raw bytes with
+// raw, the merged transcript with combined, else stdout and stderr apart.
+func writeOutput(stdout, stderr io.Writer, out bridge.Output, raw, combined bool) {
+	switch {
+	case raw:
+		stdout.Write(out.StdoutRaw)
+		stderr.Write(out.StderrRaw)
+	case combined:
+		if transcript := out.Transcript(); transcript != "" {
+			fmt.Fprintln(stdout, transcript)
+		}
+	default:
+		if out.Stdout != "" {
+			fmt.Fprintln(stdout, out.Stdout)
+		}
+		if out.Stderr != "" {
+			fmt.Fprintln(stderr, out.Stderr)
+		}
+	}
+}
@@ -0,0 +1,37 @@
+package main
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/sibikrish3000/gowinbridge/pkg/bridge"
+)
+
+func TestWriteOutput(t *testing.T) {
+	// Partial output of a command stopped by a timeout.
+	out := bridge.Output{
+		Stdout:            "step 1\nstep 2",
+		Stderr:            "warning",
+		StdoutRaw:         []byte("step 1\r\nstep 2\r\n"),
+		StderrRaw:         []byte("warning\r\n"),
+		Combined:          []bridge.OutputEvent{{Stream: bridge.StreamStdout, Line: "step 1"}, {Stream: bridge.StreamStderr, Line: "warning"}},
+		TerminationReason: bridge.TerminationTimeout,
+	}
+
+	tests := []struct {
+		name             string
+		raw, combined    bool
+		wantOut, wantErr string
+	}{
+		{"split", false, false, "step 1\nstep 2\n", "warning\n"},
+		{"raw", true, false, "step 1\r\nstep 2\r\n", "warning\r\n"},
+		{"combined", false, true, "step 1\nwarning\n", ""},
+	}
+	for _, tt := range tests {
+		var stdout, stderr bytes.Buffer
+		writeOutput(&stdout, &stderr, out, tt.raw, tt.combined)
+		if stdout.String() != tt.wantOut || stderr.String() != tt.wantErr {
+			t.Errorf("%s: stdout %q, stderr %q; want %q, %q", tt.name, stdout.String(), stderr.String(), tt.wantOut, tt.wantErr)
+		}
+	}
+}
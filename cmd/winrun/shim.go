@@ -11,13 +11,13 @@ import (
 const shimTemplate = `#!/bin/sh
 # Generated by winrun shim install
 # Binary: %s
-exec winrun --convert-paths -- %s "$@"
+exec winrun --convert-paths%s -- %s "$@"
 `
 
 // handleShim processes the "shim" subcommand.
 // Usage:
 //
-//	winrun shim install <binary.exe> --as <name> [--bin-dir PATH]
+//	winrun shim install <binary.exe|script.cmd|script.ps1> --as <name> [--bin-dir PATH] [--path-arg N] [--no-path-arg N]
 //	winrun shim list [--bin-dir PATH]
 //	winrun shim remove <name> [--bin-dir PATH]
 func handleShim(args []string) {
@@ -55,18 +55,28 @@ func shimInstall(args []string) {
 	fs := flag.NewFlagSet("shim install", flag.ExitOnError)
 	asName := fs.String("as", "", "Name for the shim (required)")
 	binDir := fs.String("bin-dir", defaultBinDir(), "Directory to install the shim script")
-	fs.Parse(args)
+	var argFlags argPolicyFlags
+	argFlags.register(fs)
+	positional := parseInterspersed(fs, args)
 
-	if fs.NArg() == 0 {
-		fmt.Fprintln(os.Stderr, "Usage: winrun shim install <binary.exe> --as <name> [--bin-dir PATH]")
+	if len(positional) != 1 {
+		fmt.Fprintln(os.Stderr, "Usage: winrun shim install <binary.exe|script.bat|script.cmd|script.ps1> --as <name> [--bin-dir PATH] [--path-arg N] [--no-path-arg N]")
 		os.Exit(1)
 	}
 
-	binary := fs.Arg(0)
+	binary := positional[0]
+	if strings.Contains(binary, "/") {
+		// The shim runs from any directory, so pin relative script paths.
+		abs, err := filepath.Abs(binary)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error resolving %q: %v\n", binary, err)
+			os.Exit(1)
+		}
+		binary = abs
+	}
 	name := *asName
 	if name == "" {
-		// Auto-derive name from binary: "docker.exe" → "docker"
-		name = strings.TrimSuffix(filepath.Base(binary), ".exe")
+		name = shimName(binary)
 	}
 
 	// Ensure bin directory exists.
@@ -76,7 +86,7 @@ func shimInstall(args []string) {
 	}
 
 	shimPath := filepath.Join(*binDir, name)
-	content := fmt.Sprintf(shimTemplate, binary, binary)
+	content := generateShimScript(binary, argFlags.args()...)
 
 	if err := os.WriteFile(shimPath, []byte(content), 0o755); err != nil {
 		fmt.Fprintf(os.Stderr, "Error writing shim %q: %v\n", shimPath, err)
@@ -87,6 +97,39 @@ func shimInstall(args []string) {
 	fmt.Printf("Make sure %s is in your PATH.\n", *binDir)
 }
 
+// parseInterspersed parses args with fs and returns the positional
+// arguments. Unlike fs.Parse alone, which stops at the first positional
+// argument, it also honours flags that follow one, as in
+// "install rg.exe --as rg --no-path-arg 1".
+func parseInterspersed(fs *flag.FlagSet, args []string) []string {
+	var positional []string
+	for {
+		fs.Parse(args)
+		if fs.NArg() == 0 {
+			return positional
+		}
+		positional = append(positional, fs.Arg(0))
+		args = fs.Args()[1:]
+	}
+}
+
+// shimExts are the extensions stripped from a binary or script to derive
+// its shim name.
+var shimExts = []string{".exe", ".com", ".bat", ".cmd", ".ps1"}
+
+// shimName derives a shim name from binary: "docker.exe" → "docker",
+// "./scripts/Build.cmd" → "Build".
+func shimName(binary string) string {
+	name := binary[strings.LastIndexAny(binary, `/\`)+1:]
+	ext := filepath.Ext(name)
+	for _, known := range shimExts {
+		if strings.EqualFold(ext, known) {
+			return strings.TrimSuffix(name, ext)
+		}
+	}
+	return name
+}
+
 func shimList(args []string) {
 	fs := flag.NewFlagSet("shim list", flag.ExitOnError)
 	binDir := fs.String("bin-dir", defaultBinDir(), "Directory to search for shims")
@@ -118,7 +161,7 @@ func shimList(args []string) {
 			binary := "unknown"
 			for _, line := range lines {
 				if strings.HasPrefix(line, "# Binary: ") {
-					binary = strings.TrimPrefix(line, "# Binary: ")
+					binary = shellUnquote(strings.TrimPrefix(line, "# Binary: "))
 					break
 				}
 			}
@@ -137,14 +180,14 @@ func shimList(args []string) {
 func shimRemove(args []string) {
 	fs := flag.NewFlagSet("shim remove", flag.ExitOnError)
 	binDir := fs.String("bin-dir", defaultBinDir(), "Directory containing the shim")
-	fs.Parse(args)
+	positional := parseInterspersed(fs, args)
 
-	if fs.NArg() == 0 {
+	if len(positional) != 1 {
 		fmt.Fprintln(os.Stderr, "Usage: winrun shim remove <name> [--bin-dir PATH]")
 		os.Exit(1)
 	}
 
-	name := fs.Arg(0)
+	name := positional[0]
 	shimPath := filepath.Join(*binDir, name)
 
 	// Verify it's actually a winrun shim before removing.
@@ -167,8 +210,29 @@ func shimRemove(args []string) {
 	fmt.Printf("Shim removed: %s\n", shimPath)
 }
 
-// generateShimScript produces the shell script content for a shim.
+// generateShimScript produces the shell script content for a shim that
+// passes winrunArgs, such as "--no-path-arg 2", before the command.
 // Exported for testing.
-func generateShimScript(binary string) string {
-	return fmt.Sprintf(shimTemplate, binary, binary)
+func generateShimScript(binary string, winrunArgs ...string) string {
+	extra := ""
+	if len(winrunArgs) > 0 {
+		extra = " " + strings.Join(winrunArgs, " ")
+	}
+	quoted := shellQuote(binary)
+	return fmt.Sprintf(shimTemplate, quoted, extra, quoted)
+}
+
+// shellQuote quotes s as a single sh word: spaces, $ and the like in a
+// script path are taken literally.
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+// shellUnquote reverses shellQuote, returning s unchanged if it is not
+// quoted, as in shims written before binaries were quoted.
+func shellUnquote(s string) string {
+	if len(s) < 2 || s[0] != '\'' || s[len(s)-1] != '\'' {
+		return s
+	}
+	return strings.ReplaceAll(s[1:len(s)-1], `'\''`, "'")
 }
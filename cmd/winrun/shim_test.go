@@ -1,7 +1,9 @@
 package main
 
 import (
+	"flag"
 	"os"
+	"os/exec"
 	"path/filepath"
 	"strings"
 	"testing"
@@ -27,6 +29,63 @@ func TestGenerateShimScript(t *testing.T) {
 	}
 }
 
+func TestGenerateShimScript_ArgFlags(t *testing.T) {
+	script := generateShimScript("/mnt/c/tools/rg.exe", "--no-path-arg", "1")
+	want := `exec winrun --convert-paths --no-path-arg 1 -- '/mnt/c/tools/rg.exe' "$@"`
+	if !strings.Contains(script, want) {
+		t.Errorf("shim script missing %q:\n%s", want, script)
+	}
+}
+
+func TestGenerateShimScript_QuotesPath(t *testing.T) {
+	if _, err := exec.LookPath("sh"); err != nil {
+		t.Skip("sh not available")
+	}
+	// A stand-in winrun prints the arguments the shim passes it.
+	dir := t.TempDir()
+	fake := "#!/bin/sh\nprintf '%s\\n' \"$@\"\n"
+	if err := os.WriteFile(filepath.Join(dir, "winrun"), []byte(fake), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("PATH", dir+string(os.PathListSeparator)+os.Getenv("PATH"))
+
+	binary := "/home/me/My Project/it's $HOME.cmd"
+	shim := filepath.Join(dir, "build")
+	if err := os.WriteFile(shim, []byte(generateShimScript(binary)), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	out, err := exec.Command(shim, "a b").Output()
+	if err != nil {
+		t.Fatalf("running shim: %v", err)
+	}
+	want := "--convert-paths\n--\n" + binary + "\na b\n"
+	if string(out) != want {
+		t.Errorf("shim passed %q, want %q", out, want)
+	}
+	if got := shellUnquote(shellQuote(binary)); got != binary {
+		t.Errorf("shellUnquote(shellQuote(%q)) = %q", binary, got)
+	}
+}
+
+func TestParseInterspersed(t *testing.T) {
+	fs := flag.NewFlagSet("shim install", flag.ContinueOnError)
+	asName := fs.String("as", "", "")
+	var argFlags argPolicyFlags
+	argFlags.register(fs)
+
+	positional := parseInterspersed(fs, []string{"--path-arg", "2", "rg.exe", "--as", "rg", "--no-path-arg", "1"})
+	if len(positional) != 1 || positional[0] != "rg.exe" {
+		t.Errorf("positional = %q, want [rg.exe]", positional)
+	}
+	if *asName != "rg" {
+		t.Errorf("--as = %q, want rg", *asName)
+	}
+	want := []string{"--path-arg", "2", "--no-path-arg", "1"}
+	if got := argFlags.args(); strings.Join(got, " ") != strings.Join(want, " ") {
+		t.Errorf("arg flags = %q, want %q", got, want)
+	}
+}
+
 func TestShimInstallAndRemove(t *testing.T) {
 	// Use a temp directory as the bin dir.
 	tmpDir := t.TempDir()
@@ -83,3 +142,19 @@ func TestShimAutoDeriveName(t *testing.T) {
 		t.Errorf("auto-derived name = %q, want %q", name, "docker")
 	}
 }
+
+func TestShimName(t *testing.T) {
+	tests := map[string]string{
+		"docker.exe":              "docker",
+		"/home/me/repo/Build.CMD": "Build",
+		"setup.bat":               "setup",
+		`C:\tools\deploy.ps1`:     "deploy",
+		"/usr/local/bin/tool.v2":  "tool.v2",
+		"kubectl":                 "kubectl",
+	}
+	for in, want := range tests {
+		if got := shimName(in); got != want {
+			t.Errorf("shimName(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
@@ -0,0 +1,49 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/sibikrish3000/gowinbridge/pkg/bridge"
+	"github.com/sibikrish3000/gowinbridge/pkg/wslpath"
+)
+
+// handleWhich processes the "which" subcommand.
+// Usage:
+//
+//	winrun which <name>...
+func handleWhich(args []string) {
+	if len(args) == 0 {
+		fmt.Fprintln(os.Stderr, "Usage: winrun which <name>...")
+		os.Exit(1)
+	}
+
+	status := 0
+	for _, name := range args {
+		r, err := bridge.Which(name)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "winrun which: %v\n", err)
+			status = 1
+			continue
+		}
+		fmt.Print(formatResolution(r))
+	}
+	os.Exit(status)
+}
+
+// formatResolution renders r for "winrun which".
+func formatResolution(r bridge.Resolution) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s\n", r.Command)
+	if r.Path != "" {
+		fmt.Fprintf(&b, "  Linux path:   %s\n", r.Path)
+		if winPath, err := wslpath.ToWindowsPath(r.Path); err == nil {
+			fmt.Fprintf(&b, "  Windows path: %s\n", winPath)
+		}
+	} else {
+		fmt.Fprintf(&b, "  Windows path: %s\n", r.Name)
+	}
+	fmt.Fprintf(&b, "  Resolved via: %s\n", r.Via)
+	return b.String()
+}
@@ -0,0 +1,33 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/sibikrish3000/gowinbridge/pkg/bridge"
+)
+
+func TestFormatResolution(t *testing.T) {
+	t.Setenv("WSL_DISTRO_NAME", "Ubuntu")
+	out := formatResolution(bridge.Resolution{
+		Command: "cmd",
+		Name:    "cmd.exe",
+		Path:    "/mnt/c/Windows/System32/cmd.exe",
+		Via:     "PATH, PATHEXT .EXE",
+	})
+	for _, want := range []string{
+		"cmd\n",
+		"Linux path:   /mnt/c/Windows/System32/cmd.exe\n",
+		"Windows path: ",
+		"Resolved via: PATH, PATHEXT .EXE\n",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("output missing %q:\n%s", want, out)
+		}
+	}
+
+	out = formatResolution(bridge.Resolution{Command: `C:\x.exe`, Name: `C:\x.exe`, Via: "Windows path"})
+	if !strings.Contains(out, `Windows path: C:\x.exe`) || strings.Contains(out, "Linux path") {
+		t.Errorf("unexpected output for unmapped Windows path:\n%s", out)
+	}
+}
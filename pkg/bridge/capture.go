@@ -0,0 +1,309 @@
+package bridge
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// outputSink serializes OutputEvent delivery from the stdout and stderr
+// readers so that OnOutput handlers never run concurrently, and records
+// the merged event sequence when combined output is requested.
+type outputSink struct {
+	mu sync.Mutex
+	fn func(OutputEvent)
+
+	combined bool
+	limit    int64
+	kept     map[Stream]int64
+	events   []OutputEvent
+}
+
+// newOutputSink returns a sink for config, or nil if neither OnOutput nor
+// Combined is set.
+func newOutputSink(config CommandConfig) *outputSink {
+	if config.OnOutput == nil && !config.Combined {
+		return nil
+	}
+	return &outputSink{
+		fn:       config.OnOutput,
+		combined: config.Combined,
+		limit:    config.MaxOutputBytes,
+		kept:     make(map[Stream]int64),
+	}
+}
+
+// emit delivers a single line to the handler. It is a no-op on a nil sink.
+// Once a stream has recorded MaxOutputBytes of combined output, its
+// further lines are still delivered but no longer recorded.
+func (s *outputSink) emit(stream Stream, line string) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	ev := OutputEvent{Stream: stream, Line: line, Time: time.Now()}
+	if s.combined {
+		n := int64(len(line)) + 1
+		if s.limit <= 0 || s.kept[stream]+n <= s.limit {
+			s.kept[stream] += n
+			s.events = append(s.events, ev)
+		}
+	}
+	if s.fn != nil {
+		s.fn(ev)
+	}
+}
+
+// recorded returns the merged events recorded so far.
+func (s *outputSink) recorded() []OutputEvent {
+	if s == nil {
+		return nil
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.events
+}
+
+// captureLines reads r line by line, appending each line with its original
+// terminator to buf and forwarding it, without the terminator, to sink as
+// it arrives. If rewrite is not nil, it is applied to each line, without
+// the terminator, before either.
+//
+// Lines may be of any length. If limit is positive, a line longer than
+// limit is not held in memory whole: it is passed on in pieces of limit
+// bytes, each its own event, and is not rewritten.
+//
+// If reading r fails, the error is returned after pipe has been drained,
+// so the child never blocks writing to a pipe nobody reads. pipe is the
+// undecoded source of r and may be the same reader.
+func captureLines(r, pipe io.Reader, stream Stream, buf io.StringWriter, sink *outputSink, rewrite func(string) string, limit int64) error {
+	br := bufio.NewReader(r)
+	var line []byte
+	split := false // the current line has been passed on in pieces
+	for {
+		chunk, err := br.ReadSlice('\n')
+		line = append(line, chunk...)
+		n := len(line) // length without the terminator
+		if err != bufio.ErrBufferFull {
+			n = len(bytes.TrimSuffix(bytes.TrimSuffix(line, []byte("\n")), []byte("\r")))
+		}
+		for limit > 0 && int64(n) > limit {
+			piece := string(line[:limit])
+			buf.WriteString(piece)
+			sink.emit(stream, piece)
+			line = append(line[:0], line[limit:]...)
+			n -= int(limit)
+			split = true
+		}
+		if err == bufio.ErrBufferFull {
+			continue
+		}
+		if len(line) > 0 {
+			full := string(line)
+			text := strings.TrimSuffix(strings.TrimSuffix(full, "\n"), "\r")
+			if rewrite != nil && !split {
+				rewritten := rewrite(text)
+				full = rewritten + full[len(text):]
+				text = rewritten
+			}
+			buf.WriteString(full)
+			sink.emit(stream, text)
+			line = line[:0]
+			split = false
+		}
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			io.Copy(io.Discard, pipe)
+			return fmt.Errorf("failed to read %s: %w", stream, err)
+		}
+	}
+}
+
+// normalizeNewlines applies policy to captured text.
+func normalizeNewlines(s string, policy NewlinePolicy) string {
+	switch policy {
+	case NewlineKeep:
+		return s
+	case NewlineLF:
+		return strings.ReplaceAll(s, "\r\n", "\n")
+	case NewlineTrimTrailing:
+		return strings.TrimRight(s, "\r\n")
+	default:
+		return strings.TrimRight(strings.ReplaceAll(s, "\r\n", "\n"), "\n")
+	}
+}
+
+// boundedBuffer retains at most limit bytes of everything written to it:
+// the first half and the most recent half. A limit of zero or less keeps
+// everything. The cut between head and tail may split a multi-byte
+// character.
+type boundedBuffer struct {
+	limit int64
+	head  []byte
+	tail  []byte
+	total int64
+}
+
+// Write implements io.Writer. It never fails.
+func (b *boundedBuffer) Write(p []byte) (int, error) {
+	n := len(p)
+	b.total += int64(n)
+	if b.limit <= 0 {
+		b.head = append(b.head, p...)
+		return n, nil
+	}
+
+	headMax, tailMax := b.split()
+	if room := headMax - len(b.head); room > 0 {
+		k := min(room, len(p))
+		b.head = append(b.head, p[:k]...)
+		p = p[k:]
+	}
+	if len(p) == 0 {
+		return n, nil
+	}
+
+	// Let the tail grow to twice its size before compacting, so that
+	// retention stays amortized O(1) per byte.
+	b.tail = append(b.tail, p...)
+	if len(b.tail) > 2*tailMax {
+		b.tail = append(b.tail[:0], b.tail[len(b.tail)-tailMax:]...)
+	}
+	return n, nil
+}
+
+// WriteString implements io.StringWriter.
+func (b *boundedBuffer) WriteString(s string) (int, error) {
+	return b.Write([]byte(s))
+}
+
+// split returns the head and tail capacities.
+func (b *boundedBuffer) split() (int, int) {
+	headMax := int(b.limit / 2)
+	return headMax, int(b.limit) - headMax
+}
+
+// Bytes returns the retained bytes: the head followed by the tail.
+func (b *boundedBuffer) Bytes() []byte {
+	if b.limit <= 0 {
+		return b.head
+	}
+	_, tailMax := b.split()
+	tail := b.tail
+	if len(tail) > tailMax {
+		tail = tail[len(tail)-tailMax:]
+	}
+	out := make([]byte, 0, len(b.head)+len(tail))
+	out = append(out, b.head...)
+	return append(out, tail...)
+}
+
+// String returns the retained bytes as a string.
+func (b *boundedBuffer) String() string {
+	return string(b.Bytes())
+}
+
+// Dropped returns the number of bytes written but not retained.
+func (b *boundedBuffer) Dropped() int64 {
+	if b.limit <= 0 || b.total <= b.limit {
+		return 0
+	}
+	return b.total - b.limit
+}
+
+// streamCapture holds the per-stream state of a buffered execution:
+// the decoded text, the optional raw bytes, and the optional spill file.
+type streamCapture struct {
+	stream  Stream
+	src     io.Reader // the pipe, teed into raw and spill as it is read
+	reader  io.Reader // src after decoding
+	rewrite func(string) string
+	limit   int64
+	text    *boundedBuffer
+	raw     *boundedBuffer
+	spill   *os.File
+}
+
+// newStreamCapture prepares capture of pipe according to config.
+// It must be called before the process starts.
+func newStreamCapture(stream Stream, pipe io.Reader, config CommandConfig) (*streamCapture, error) {
+	c := &streamCapture{
+		stream: stream,
+		src:    pipe,
+		limit:  config.MaxOutputBytes,
+		text:   &boundedBuffer{limit: config.MaxOutputBytes},
+	}
+	if config.RewriteOutputPaths {
+		c.rewrite = RewriteWindowsPaths
+	}
+
+	var tees []io.Writer
+	if config.MaxOutputBytes > 0 && config.SpillDir != "" {
+		f, err := os.CreateTemp(config.SpillDir, "winrun-"+stream.String()+"-*.log")
+		if err != nil {
+			return nil, fmt.Errorf("failed to create %s spill file: %w", stream, err)
+		}
+		c.spill = f
+		tees = append(tees, f)
+	}
+	if config.RawOutput {
+		c.raw = &boundedBuffer{limit: config.MaxOutputBytes}
+		tees = append(tees, c.raw)
+	}
+	if len(tees) > 0 {
+		c.src = io.TeeReader(pipe, io.MultiWriter(tees...))
+	}
+
+	reader, err := NewDecodingReader(c.src, config.Encoding)
+	if err != nil {
+		c.discard()
+		return nil, fmt.Errorf("failed to create %s decoder: %w", stream, err)
+	}
+	c.reader = reader
+	return c, nil
+}
+
+// run reads the stream to EOF, forwarding lines to sink.
+func (c *streamCapture) run(sink *outputSink) error {
+	return captureLines(c.reader, c.src, c.stream, c.text, sink, c.rewrite, c.limit)
+}
+
+// truncation closes the spill file and reports what was dropped. The
+// spill file is kept only if something was actually truncated.
+func (c *streamCapture) truncation() (Truncation, error) {
+	t := Truncation{DroppedBytes: c.text.Dropped()}
+	if c.raw != nil {
+		t.DroppedRawBytes = c.raw.Dropped()
+	}
+	t.Truncated = t.DroppedBytes > 0 || t.DroppedRawBytes > 0
+
+	if c.spill == nil {
+		return t, nil
+	}
+	if !t.Truncated {
+		c.discard()
+		return t, nil
+	}
+	if err := c.spill.Close(); err != nil {
+		return t, fmt.Errorf("failed to write %s spill file: %w", c.stream, err)
+	}
+	t.SpillFile = c.spill.Name()
+	return t, nil
+}
+
+// discard closes and removes the spill file, if any.
+func (c *streamCapture) discard() {
+	if c.spill != nil {
+		c.spill.Close()
+		os.Remove(c.spill.Name())
+		c.spill = nil
+	}
+}
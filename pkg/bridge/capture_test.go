@@ -0,0 +1,396 @@
+package bridge
+
+import (
+	"errors"
+	"os"
+	"os/exec"
+	"strings"
+	"testing"
+)
+
+func TestCaptureLines_EmitsEvents(t *testing.T) {
+	var events []OutputEvent
+	sink := newOutputSink(CommandConfig{OnOutput: func(ev OutputEvent) {
+		events = append(events, ev)
+	}})
+
+	var buf strings.Builder
+	r := strings.NewReader("one\ntwo\n")
+	if err := captureLines(r, r, StreamStdout, &buf, sink, nil, 0); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if buf.String() != "one\ntwo\n" {
+		t.Errorf("buffer = %q, want %q", buf.String(), "one\ntwo\n")
+	}
+	if len(events) != 2 {
+		t.Fatalf("got %d events, want 2", len(events))
+	}
+	for i, want := range []string{"one", "two"} {
+		if events[i].Line != want || events[i].Stream != StreamStdout {
+			t.Errorf("event[%d] = %+v, want stdout %q", i, events[i], want)
+		}
+		if events[i].Time.IsZero() {
+			t.Errorf("event[%d] has zero timestamp", i)
+		}
+	}
+}
+
+func TestCaptureLines_Rewrite(t *testing.T) {
+	var events []OutputEvent
+	sink := newOutputSink(CommandConfig{OnOutput: func(ev OutputEvent) { events = append(events, ev) }})
+	r := strings.NewReader("C:\\src\\a.c(1): error\r\nok\n")
+	var buf strings.Builder
+	if err := captureLines(r, r, StreamStdout, &buf, sink, RewriteWindowsPaths, 0); err != nil {
+		t.Fatal(err)
+	}
+	if want := "/mnt/c/src/a.c(1): error\r\nok\n"; buf.String() != want {
+		t.Errorf("buffer = %q, want %q", buf.String(), want)
+	}
+	if len(events) != 2 || events[0].Line != "/mnt/c/src/a.c(1): error" {
+		t.Errorf("events = %+v", events)
+	}
+}
+
+func TestCaptureLines_NilSink(t *testing.T) {
+	var buf strings.Builder
+	r := strings.NewReader("quiet")
+	if err := captureLines(r, r, StreamStderr, &buf, newOutputSink(CommandConfig{}), nil, 0); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if buf.String() != "quiet" {
+		t.Errorf("buffer = %q, want %q", buf.String(), "quiet")
+	}
+}
+
+func TestCaptureLines_LongLine(t *testing.T) {
+	// Well beyond bufio.Scanner's default 64 KiB token limit.
+	long := strings.Repeat("x", 1<<20)
+	r := strings.NewReader(long + "\r\nafter\n")
+
+	var buf strings.Builder
+	if err := captureLines(r, r, StreamStdout, &buf, nil, nil, 0); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if buf.String() != long+"\r\nafter\n" {
+		t.Errorf("long line not captured intact (got %d bytes)", buf.Len())
+	}
+}
+
+// failingReader returns data followed by a non-EOF error.
+type failingReader struct {
+	data []byte
+	err  error
+}
+
+func (f *failingReader) Read(p []byte) (int, error) {
+	if len(f.data) == 0 {
+		return 0, f.err
+	}
+	n := copy(p, f.data)
+	f.data = f.data[n:]
+	return n, nil
+}
+
+func TestCaptureLines_ReadErrorDrainsPipe(t *testing.T) {
+	boom := errors.New("decoder exploded")
+	decoded := &failingReader{data: []byte("partial\n"), err: boom}
+	pipe := strings.NewReader("unread pipe contents")
+
+	var buf strings.Builder
+	err := captureLines(decoded, pipe, StreamStderr, &buf, nil, nil, 0)
+	if !errors.Is(err, boom) {
+		t.Fatalf("error = %v, want wrapped %v", err, boom)
+	}
+	if !strings.Contains(err.Error(), "stderr") {
+		t.Errorf("error %q does not name the stream", err)
+	}
+	if buf.String() != "partial\n" {
+		t.Errorf("buffer = %q, want data read before the error", buf.String())
+	}
+	if pipe.Len() != 0 {
+		t.Errorf("pipe not drained: %d bytes left", pipe.Len())
+	}
+}
+
+func TestExecuteBuffered_LargeOutputNoDeadlock(t *testing.T) {
+	if _, err := exec.LookPath("sh"); err != nil {
+		t.Skip("sh not available")
+	}
+
+	// A single 256 KiB line on stdout followed by more output on both streams.
+	cmd := exec.Command("sh", "-c", "head -c 262144 /dev/zero | tr '\\0' 'a'; echo; echo done; echo err >&2")
+	out, err := executeBuffered(&command{Cmd: cmd}, CommandConfig{Command: "sh"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	lines := strings.Split(out.Stdout, "\n")
+	if len(lines) != 2 || len(lines[0]) != 262144 || lines[1] != "done" {
+		t.Errorf("unexpected stdout shape: %d lines", len(lines))
+	}
+	if out.Stderr != "err" {
+		t.Errorf("stderr = %q, want %q", out.Stderr, "err")
+	}
+}
+
+func TestNormalizeNewlines(t *testing.T) {
+	const in = "a\r\nb\r\n\r\n"
+	tests := []struct {
+		policy NewlinePolicy
+		want   string
+	}{
+		{NewlineDefault, "a\nb"},
+		{NewlineKeep, in},
+		{NewlineLF, "a\nb\n\n"},
+		{NewlineTrimTrailing, "a\r\nb"},
+	}
+	for _, tt := range tests {
+		if got := normalizeNewlines(in, tt.policy); got != tt.want {
+			t.Errorf("normalizeNewlines(%q, %d) = %q, want %q", in, tt.policy, got, tt.want)
+		}
+	}
+}
+
+func TestExecuteBuffered_RawOutput(t *testing.T) {
+	if _, err := exec.LookPath("sh"); err != nil {
+		t.Skip("sh not available")
+	}
+
+	// CRLF line endings, a NUL byte and a trailing blank line must survive.
+	script := `printf 'one\r\ntwo\000\r\n\r\n'; printf 'warn\n' >&2`
+	config := CommandConfig{Command: "sh", RawOutput: true, Newlines: NewlineKeep}
+	out, err := executeBuffered(&command{Cmd: exec.Command("sh", "-c", script)}, config)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	wantRaw := "one\r\ntwo\x00\r\n\r\n"
+	if string(out.StdoutRaw) != wantRaw {
+		t.Errorf("StdoutRaw = %q, want %q", out.StdoutRaw, wantRaw)
+	}
+	if out.Stdout != wantRaw {
+		t.Errorf("Stdout with NewlineKeep = %q, want %q", out.Stdout, wantRaw)
+	}
+	if string(out.StderrRaw) != "warn\n" {
+		t.Errorf("StderrRaw = %q, want %q", out.StderrRaw, "warn\n")
+	}
+}
+
+func TestExecuteBuffered_RawBeforeDecoding(t *testing.T) {
+	if _, err := exec.LookPath("sh"); err != nil {
+		t.Skip("sh not available")
+	}
+
+	// "café" in CP1252: the raw bytes stay CP1252, the text is UTF-8.
+	config := CommandConfig{Command: "sh", RawOutput: true, Encoding: EncodingCP1252}
+	out, err := executeBuffered(&command{Cmd: exec.Command("sh", "-c", `printf 'caf\351'`)}, config)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if string(out.StdoutRaw) != "caf\xe9" {
+		t.Errorf("StdoutRaw = %q, want CP1252 bytes", out.StdoutRaw)
+	}
+	if out.Stdout != "café" {
+		t.Errorf("Stdout = %q, want %q", out.Stdout, "café")
+	}
+}
+
+func TestBoundedBuffer(t *testing.T) {
+	tests := []struct {
+		name    string
+		limit   int64
+		writes  []string
+		want    string
+		dropped int64
+	}{
+		{"unlimited", 0, []string{"abc", "def"}, "abcdef", 0},
+		{"under limit", 8, []string{"abc", "def"}, "abcdef", 0},
+		{"exactly limit", 6, []string{"abc", "def"}, "abcdef", 0},
+		{"head and tail kept", 4, []string{"ab", "cdef", "gh"}, "abgh", 4},
+		{"single large write", 4, []string{"0123456789"}, "0189", 6},
+		{"many small writes", 6, []string{"a", "b", "c", "d", "e", "f", "g", "h", "i", "j"}, "abchij", 4},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := &boundedBuffer{limit: tt.limit}
+			for _, w := range tt.writes {
+				b.WriteString(w)
+			}
+			if got := b.String(); got != tt.want {
+				t.Errorf("String() = %q, want %q", got, tt.want)
+			}
+			if got := b.Dropped(); got != tt.dropped {
+				t.Errorf("Dropped() = %d, want %d", got, tt.dropped)
+			}
+		})
+	}
+}
+
+func TestExecuteBuffered_MaxOutputBytes(t *testing.T) {
+	if _, err := exec.LookPath("sh"); err != nil {
+		t.Skip("sh not available")
+	}
+
+	spillDir := t.TempDir()
+	config := CommandConfig{
+		Command:        "sh",
+		MaxOutputBytes: 10,
+		SpillDir:       spillDir,
+		RawOutput:      true,
+		Newlines:       NewlineKeep,
+	}
+	// 20 bytes of stdout, 3 bytes of stderr.
+	script := `printf '0123456789abcdefghij'; printf 'err' >&2`
+	out, err := executeBuffered(&command{Cmd: exec.Command("sh", "-c", script)}, config)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if out.Stdout != "01234fghij" {
+		t.Errorf("Stdout = %q, want head and tail %q", out.Stdout, "01234fghij")
+	}
+	tr := out.StdoutTruncation
+	if !tr.Truncated || tr.DroppedBytes != 10 || tr.DroppedRawBytes != 10 {
+		t.Errorf("StdoutTruncation = %+v, want 10 text and raw bytes dropped", tr)
+	}
+	if !out.Truncated() {
+		t.Error("Output.Truncated() = false, want true")
+	}
+
+	spilled, err := os.ReadFile(tr.SpillFile)
+	if err != nil {
+		t.Fatalf("reading spill file: %v", err)
+	}
+	if string(spilled) != "0123456789abcdefghij" {
+		t.Errorf("spill file = %q, want the complete stream", spilled)
+	}
+
+	if out.StderrTruncation != (Truncation{}) {
+		t.Errorf("StderrTruncation = %+v, want zero value", out.StderrTruncation)
+	}
+	// Untruncated streams must not leave spill files behind.
+	entries, _ := os.ReadDir(spillDir)
+	if len(entries) != 1 {
+		t.Errorf("spill dir has %d files, want 1", len(entries))
+	}
+}
+
+func TestExecuteBuffered_OnOutput(t *testing.T) {
+	if _, err := exec.LookPath("sh"); err != nil {
+		t.Skip("sh not available")
+	}
+
+	var stdout, stderr []string
+	config := CommandConfig{
+		Command: "sh",
+		OnOutput: func(ev OutputEvent) {
+			switch ev.Stream {
+			case StreamStdout:
+				stdout = append(stdout, ev.Line)
+			case StreamStderr:
+				stderr = append(stderr, ev.Line)
+			}
+		},
+	}
+	cmd := exec.Command("sh", "-c", "echo a; echo b >&2; echo c")
+
+	out, err := executeBuffered(&command{Cmd: cmd}, config)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if strings.Join(stdout, ",") != "a,c" {
+		t.Errorf("streamed stdout = %v, want [a c]", stdout)
+	}
+	if strings.Join(stderr, ",") != "b" {
+		t.Errorf("streamed stderr = %v, want [b]", stderr)
+	}
+	if out.Stdout != "a\nc" || out.Stderr != "b" {
+		t.Errorf("final output = %+v, want stdout %q stderr %q", out, "a\nc", "b")
+	}
+}
+
+func TestOutputSink_CombinedRespectsLimit(t *testing.T) {
+	sink := newOutputSink(CommandConfig{Combined: true, MaxOutputBytes: 8})
+	sink.emit(StreamStdout, "out1") // 5 bytes with newline
+	sink.emit(StreamStderr, "err1")
+	sink.emit(StreamStdout, "out2") // would exceed stdout's 8 bytes
+	sink.emit(StreamStderr, "er")
+
+	got := Output{Combined: sink.recorded()}.Transcript()
+	if got != "out1\nerr1\ner" {
+		t.Errorf("Transcript() = %q, want %q", got, "out1\nerr1\ner")
+	}
+}
+
+func TestExecuteBuffered_Combined(t *testing.T) {
+	if _, err := exec.LookPath("sh"); err != nil {
+		t.Skip("sh not available")
+	}
+
+	// Sleeps give each line time to be read before the next is written.
+	script := "echo one; sleep 0.05; echo two >&2; sleep 0.05; echo three"
+	config := CommandConfig{Command: "sh", Combined: true}
+	out, err := executeBuffered(&command{Cmd: exec.Command("sh", "-c", script)}, config)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if got := out.Transcript(); got != "one\ntwo\nthree" {
+		t.Errorf("Transcript() = %q, want %q", got, "one\ntwo\nthree")
+	}
+	wantStreams := []Stream{StreamStdout, StreamStderr, StreamStdout}
+	if len(out.Combined) != len(wantStreams) {
+		t.Fatalf("got %d combined events, want %d", len(out.Combined), len(wantStreams))
+	}
+	for i, ev := range out.Combined {
+		if ev.Stream != wantStreams[i] {
+			t.Errorf("event[%d].Stream = %s, want %s", i, ev.Stream, wantStreams[i])
+		}
+		if i > 0 && ev.Time.Before(out.Combined[i-1].Time) {
+			t.Errorf("event[%d] timestamp goes backwards", i)
+		}
+	}
+	// Separate streams are still captured.
+	if out.Stdout != "one\nthree" || out.Stderr != "two" {
+		t.Errorf("separate streams = %q / %q", out.Stdout, out.Stderr)
+	}
+}
+
+func TestStreamString(t *testing.T) {
+	if StreamStdout.String() != "stdout" || StreamStderr.String() != "stderr" {
+		t.Errorf("unexpected stream names: %q, %q", StreamStdout, StreamStderr)
+	}
+	if Stream(0).String() != "unknown" {
+		t.Errorf("Stream(0) = %q, want unknown", Stream(0))
+	}
+}
+
+func TestCaptureLines_LineBeyondLimit(t *testing.T) {
+	const limit = 16
+	long := strings.Repeat("0123456789abcdef", 1000) // 1000 times the limit, no newline
+	r := strings.NewReader(long + "tail\nnext\n")
+
+	var longest int
+	var events []string
+	sink := newOutputSink(CommandConfig{OnOutput: func(ev OutputEvent) {
+		longest = max(longest, len(ev.Line))
+		events = append(events, ev.Line)
+	}})
+	buf := &boundedBuffer{limit: limit}
+	if err := captureLines(r, r, StreamStdout, buf, sink, RewriteWindowsPaths, limit); err != nil {
+		t.Fatal(err)
+	}
+
+	if longest > limit {
+		t.Errorf("longest event = %d bytes, want at most %d", longest, limit)
+	}
+	if got := strings.Join(events[:len(events)-1], ""); got != long+"tail" {
+		t.Errorf("pieces do not reassemble the line (%d bytes)", len(got))
+	}
+	if events[len(events)-1] != "next" {
+		t.Errorf("last event = %q, want the following line intact", events[len(events)-1])
+	}
+	if got, want := buf.String(), long[:8]+"il\nnext\n"; got != want {
+		t.Errorf("retained = %q, want head and tail %q", got, want)
+	}
+}
@@ -5,6 +5,7 @@ package bridge
 
 import (
 	"io"
+	"strings"
 	"time"
 )
 
@@ -30,10 +31,29 @@ type CommandConfig struct {
 	// Zero means no timeout.
 	Timeout time.Duration
 
+	// Termination controls how the command is stopped when the context is
+	// canceled or Timeout elapses. The zero value interrupts it, waits
+	// DefaultGracePeriod, then kills its Windows process tree.
+	Termination TerminationPolicy
+
 	// ConvertPaths, when true, translates file-like arguments from Linux
 	// to Windows format before execution.
 	ConvertPaths bool
 
+	// PathRules tunes how ConvertPaths finds paths in arguments.
+	PathRules PathRules
+
+	// ArgPolicies overrides path detection per argument: ArgPolicies[i]
+	// applies to Args[i]. ArgPath and ArgPathList translate even without
+	// ConvertPaths; ArgLiteral protects an argument such as a regex.
+	// Missing entries are ArgAuto and extra entries are ignored.
+	ArgPolicies []ArgPolicy
+
+	// Quoting selects how Args are quoted into the Windows command line,
+	// after path conversion. The zero value picks a rule from Command;
+	// QuoteNone passes Args to WSL interop unchanged.
+	Quoting QuoteRule
+
 	// Encoding specifies the output encoding of the Windows binary.
 	// Supported: "utf8" (default), "cp1252", "utf16le", "utf16be", "auto".
 	// When set, stdout/stderr are decoded to UTF-8 transparently.
@@ -43,9 +63,111 @@ type CommandConfig struct {
 	// If nil, the process receives no stdin.
 	Stdin io.Reader
 
-	// Interactive, when true, bypasses buffered Scanner-based capture
+	// Interactive, when true, bypasses buffered line capture
 	// and directly copies stdin/stdout/stderr for REPL/TUI support.
 	Interactive bool
+
+	// PTY, when true together with Interactive, attaches the command to a
+	// newly allocated Linux pseudo-terminal. If stdin is a terminal it is
+	// put in raw mode for the duration of the command and window size
+	// changes are forwarded, so TUI tools and readline prompts work.
+	// Stdout and stderr are merged by the terminal. Linux only.
+	PTY bool
+
+	// RewriteOutputPaths, when true, rewrites Windows paths in captured
+	// stdout and stderr lines, such as C:\src\main.c(12) in compiler
+	// errors, to Linux paths; see RewriteWindowsPaths. It applies to
+	// Output and OnOutput alike, but not to raw output, Interactive mode
+	// or Sessions.
+	RewriteOutputPaths bool
+
+	// RawOutput, when true, additionally records the exact bytes written by
+	// the process, before decoding, in Output.StdoutRaw and Output.StderrRaw.
+	// Use it for binary output or when checksums must match.
+	RawOutput bool
+
+	// Newlines controls line-ending normalization of Output.Stdout and
+	// Output.Stderr. The zero value converts CRLF to LF and strips trailing
+	// newlines. Raw output is never normalized.
+	Newlines NewlinePolicy
+
+	// MaxOutputBytes caps how much text (and raw output) is retained per
+	// stream. Once exceeded, the first and the most recent MaxOutputBytes/2
+	// bytes are kept and the middle is dropped; see Output.StdoutTruncation.
+	// A line longer than MaxOutputBytes, such as binary output without
+	// newlines, is read in pieces of MaxOutputBytes, which OnOutput receives
+	// as separate lines and RewriteOutputPaths leaves alone, so memory stays
+	// bounded. Zero means unlimited.
+	MaxOutputBytes int64
+
+	// SpillDir, if set together with MaxOutputBytes, writes the complete
+	// undecoded output of each stream to a temporary file in this directory.
+	// The file is kept only if the stream was truncated, and the caller is
+	// responsible for removing it. Use os.TempDir() for the system default.
+	SpillDir string
+
+	// Combined, when true, records stdout and stderr lines in the order they
+	// were read, tagged and timestamped, in Output.Combined. Ordering across
+	// the two streams is best-effort: they arrive on separate pipes, so
+	// lines written within microseconds of each other may be swapped.
+	// Each stream contributes at most MaxOutputBytes when that is set.
+	Combined bool
+
+	// OnOutput, if set, is called with each decoded line of stdout and
+	// stderr as soon as it is read, while the command is still running.
+	// Calls are serialized, so the handler need not be safe for concurrent
+	// use, but it should return quickly: a slow handler stalls the pipe.
+	// The final Output is populated as usual. Ignored in Interactive mode.
+	OnOutput func(OutputEvent)
+}
+
+// NewlinePolicy selects how line endings in captured text are normalized.
+type NewlinePolicy int
+
+// NewlinePolicy values.
+const (
+	// NewlineDefault converts CRLF to LF and strips trailing newlines.
+	NewlineDefault NewlinePolicy = iota
+	// NewlineKeep leaves the decoded text exactly as the process wrote it.
+	NewlineKeep
+	// NewlineLF converts CRLF to LF but keeps trailing newlines.
+	NewlineLF
+	// NewlineTrimTrailing strips trailing newlines but keeps CRLF.
+	NewlineTrimTrailing
+)
+
+// Stream identifies the standard stream a piece of output came from.
+type Stream int
+
+// Stream values.
+const (
+	StreamStdout Stream = iota + 1
+	StreamStderr
+)
+
+// String returns "stdout" or "stderr".
+func (s Stream) String() string {
+	switch s {
+	case StreamStdout:
+		return "stdout"
+	case StreamStderr:
+		return "stderr"
+	default:
+		return "unknown"
+	}
+}
+
+// OutputEvent is a single line of output delivered to CommandConfig.OnOutput
+// and recorded in Output.Combined.
+type OutputEvent struct {
+	// Stream is the stream the line was read from.
+	Stream Stream
+
+	// Line is the decoded line without its trailing newline.
+	Line string
+
+	// Time is when the line was read.
+	Time time.Time
 }
 
 // Output holds the result of a command execution.
@@ -56,9 +178,65 @@ type Output struct {
 	// Stderr is the captured standard error.
 	Stderr string
 
+	// StdoutRaw is the undecoded standard output, byte for byte.
+	// Only populated when CommandConfig.RawOutput is set.
+	StdoutRaw []byte
+
+	// StderrRaw is the undecoded standard error, byte for byte.
+	// Only populated when CommandConfig.RawOutput is set.
+	StderrRaw []byte
+
+	// Combined is the merged sequence of stdout and stderr lines.
+	// Only populated when CommandConfig.Combined is set.
+	Combined []OutputEvent
+
+	// StdoutTruncation reports what MaxOutputBytes dropped from stdout.
+	StdoutTruncation Truncation
+
+	// StderrTruncation reports what MaxOutputBytes dropped from stderr.
+	StderrTruncation Truncation
+
 	// ExitCode is the process exit code.
 	ExitCode int
 
+	// TerminationReason reports whether the command exited on its own or
+	// was stopped by a timeout or cancellation.
+	TerminationReason TerminationReason
+
 	// Duration is the wall-clock time the command took to run.
 	Duration time.Duration
 }
+
+// Transcript renders Combined as a single merged text, one line per event,
+// like the output of "command 2>&1".
+func (o Output) Transcript() string {
+	var b strings.Builder
+	for i, ev := range o.Combined {
+		if i > 0 {
+			b.WriteByte('\n')
+		}
+		b.WriteString(ev.Line)
+	}
+	return b.String()
+}
+
+// Truncated reports whether either stream was truncated by MaxOutputBytes.
+func (o Output) Truncated() bool {
+	return o.StdoutTruncation.Truncated || o.StderrTruncation.Truncated
+}
+
+// Truncation describes output dropped from one stream by MaxOutputBytes.
+type Truncation struct {
+	// Truncated is true if any bytes were dropped.
+	Truncated bool
+
+	// DroppedBytes is the number of decoded text bytes dropped.
+	DroppedBytes int64
+
+	// DroppedRawBytes is the number of raw bytes dropped (RawOutput only).
+	DroppedRawBytes int64
+
+	// SpillFile is the path of the file holding the complete undecoded
+	// stream, if SpillDir was set and the stream was truncated.
+	SpillFile string
+}
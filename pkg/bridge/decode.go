@@ -0,0 +1,201 @@
+package bridge
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"io"
+	"reflect"
+	"strings"
+)
+
+// excerptRadius is how many bytes of context DecodeError.Excerpt shows on
+// each side of the failing offset.
+const excerptRadius = 40
+
+// ExecuteJSON runs config and decodes its stdout, after encoding handling,
+// as JSON into a T. If the command fails, its error is returned and no
+// decoding is attempted. See DecodeJSON for the handling of PowerShell's
+// ConvertTo-Json output.
+func ExecuteJSON[T any](ctx context.Context, config CommandConfig) (T, Output, error) {
+	var zero T
+	output, err := Execute(ctx, config)
+	if err != nil {
+		return zero, output, err
+	}
+	v, err := DecodeJSON[T](output.Stdout)
+	return v, output, err
+}
+
+// ExecuteCSV runs config and decodes its stdout, after encoding handling,
+// as CSV with a header row. See DecodeCSV.
+func ExecuteCSV(ctx context.Context, config CommandConfig) ([]map[string]string, Output, error) {
+	output, err := Execute(ctx, config)
+	if err != nil {
+		return nil, output, err
+	}
+	records, err := DecodeCSV(output.Stdout)
+	return records, output, err
+}
+
+// DecodeJSON decodes s as JSON into a T. Parse errors are returned as a
+// *DecodeError with an excerpt of s around the failure.
+//
+// ConvertTo-Json writes nothing for an empty pipeline, a bare object for a
+// single item and an array otherwise, so when T is a slice, empty output
+// decodes to an empty slice and a single value to a slice of one. Windows
+// PowerShell's {"value": [...], "Count": n} wrapping of arrays is unwrapped.
+func DecodeJSON[T any](s string) (T, error) {
+	var v T
+	data := bytes.TrimSpace([]byte(strings.TrimPrefix(s, "\ufeff")))
+	target := reflect.ValueOf(&v).Elem()
+
+	if target.Kind() == reflect.Slice {
+		if len(data) == 0 {
+			target.Set(reflect.MakeSlice(target.Type(), 0, 0))
+			return v, nil
+		}
+		if data[0] != '[' {
+			if inner, ok := powerShellArrayWrapper(data); ok {
+				data = inner
+			} else {
+				elem := reflect.New(target.Type().Elem())
+				if err := json.Unmarshal(data, elem.Interface()); err != nil {
+					return v, newDecodeError("json", data, err)
+				}
+				target.Set(reflect.Append(reflect.MakeSlice(target.Type(), 0, 1), elem.Elem()))
+				return v, nil
+			}
+		}
+	}
+
+	if err := json.Unmarshal(data, &v); err != nil {
+		return v, newDecodeError("json", data, err)
+	}
+	return v, nil
+}
+
+// powerShellArrayWrapper returns the array inside data if data is an
+// object of exactly the keys "value" and "Count", as Windows PowerShell
+// emits for arrays passed with -InputObject.
+func powerShellArrayWrapper(data []byte) ([]byte, bool) {
+	var wrapper map[string]json.RawMessage
+	if err := json.Unmarshal(data, &wrapper); err != nil || len(wrapper) != 2 {
+		return nil, false
+	}
+	value, ok := wrapper["value"]
+	if _, hasCount := wrapper["Count"]; !ok || !hasCount {
+		return nil, false
+	}
+	value = bytes.TrimSpace(value)
+	if len(value) == 0 || value[0] != '[' {
+		return nil, false
+	}
+	return value, true
+}
+
+// DecodeCSV decodes s as CSV with a header row and returns one map per
+// record, keyed by column name. Blank lines, the stray carriage returns
+// wmic.exe writes, and the "#TYPE" line of Windows PowerShell's
+// ConvertTo-Csv are skipped. Parse errors are returned as a *DecodeError.
+func DecodeCSV(s string) ([]map[string]string, error) {
+	var lines []string
+	for _, line := range strings.Split(strings.TrimPrefix(s, "\ufeff"), "\n") {
+		line = strings.TrimRight(line, "\r")
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		if len(lines) == 0 && strings.HasPrefix(line, "#TYPE") {
+			continue
+		}
+		lines = append(lines, line)
+	}
+	data := strings.Join(lines, "\n")
+
+	r := csv.NewReader(strings.NewReader(data))
+	header, err := r.Read()
+	if err == io.EOF {
+		return []map[string]string{}, nil
+	}
+	if err != nil {
+		return nil, newDecodeError("csv", []byte(data), err)
+	}
+
+	records := []map[string]string{}
+	for {
+		fields, err := r.Read()
+		if err == io.EOF {
+			return records, nil
+		}
+		if err != nil {
+			return nil, newDecodeError("csv", []byte(data), err)
+		}
+		record := make(map[string]string, len(header))
+		for i, name := range header {
+			record[name] = fields[i]
+		}
+		records = append(records, record)
+	}
+}
+
+// newDecodeError wraps err, found while parsing data, with an excerpt
+// around the failure.
+func newDecodeError(format string, data []byte, err error) *DecodeError {
+	offset := int64(-1)
+	var (
+		syntaxErr *json.SyntaxError
+		typeErr   *json.UnmarshalTypeError
+		csvErr    *csv.ParseError
+	)
+	switch {
+	case errors.As(err, &syntaxErr):
+		offset = syntaxErr.Offset
+	case errors.As(err, &typeErr):
+		offset = typeErr.Offset
+	case errors.As(err, &csvErr):
+		offset = lineOffset(data, csvErr.StartLine)
+	}
+	if offset < 0 || offset > int64(len(data)) {
+		offset = -1
+	}
+	return &DecodeError{
+		Format:  format,
+		Offset:  offset,
+		Excerpt: excerpt(data, offset),
+		Err:     err,
+	}
+}
+
+// lineOffset returns the byte offset of the start of 1-based line n.
+func lineOffset(data []byte, n int) int64 {
+	offset := 0
+	for i := 1; i < n; i++ {
+		next := bytes.IndexByte(data[offset:], '\n')
+		if next < 0 {
+			return -1
+		}
+		offset += next + 1
+	}
+	return int64(offset)
+}
+
+// excerpt returns the part of data around offset, or its beginning if
+// offset is negative, marking elided text with "...".
+func excerpt(data []byte, offset int64) string {
+	start, end := int64(0), int64(2*excerptRadius)
+	if offset >= 0 {
+		start, end = offset-excerptRadius, offset+excerptRadius
+	}
+	start = max(start, 0)
+	end = min(end, int64(len(data)))
+	s := strings.ToValidUTF8(string(data[start:end]), "")
+	if start > 0 {
+		s = "..." + s
+	}
+	if end < int64(len(data)) {
+		s += "..."
+	}
+	return s
+}
@@ -0,0 +1,138 @@
+package bridge
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+type service struct {
+	Name   string
+	Status int
+}
+
+func TestDecodeJSON_SliceQuirks(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		want []service
+	}{
+		{"array", `[{"Name":"a","Status":4},{"Name":"b","Status":1}]`, []service{{"a", 4}, {"b", 1}}},
+		{"single object", "{\r\n  \"Name\": \"a\",\r\n  \"Status\": 4\r\n}", []service{{"a", 4}}},
+		{"empty pipeline", "", []service{}},
+		{"whitespace only", " \r\n", []service{}},
+		{"bom", "\ufeff[]", []service{}},
+		{"value wrapper", `{"value":[{"Name":"a","Status":4}],"Count":1}`, []service{{"a", 4}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := DecodeJSON[[]service](tt.in)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got == nil || !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %#v, want %#v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDecodeJSON_Scalars(t *testing.T) {
+	n, err := DecodeJSON[int]("42\n")
+	if err != nil || n != 42 {
+		t.Errorf("DecodeJSON[int] = %d, %v", n, err)
+	}
+	names, err := DecodeJSON[[]string](`"only"`)
+	if err != nil || !reflect.DeepEqual(names, []string{"only"}) {
+		t.Errorf("DecodeJSON[[]string] = %q, %v", names, err)
+	}
+	// A non-slice target keeps an object with value/Count as is.
+	m, err := DecodeJSON[map[string]any](`{"value":[1],"Count":1}`)
+	if err != nil || len(m) != 2 {
+		t.Errorf("DecodeJSON[map] = %v, %v", m, err)
+	}
+}
+
+func TestDecodeJSON_Errors(t *testing.T) {
+	out := `{"Name":"a","Status":4}` + strings.Repeat(" ", 100) + `WARNING: something`
+	_, err := DecodeJSON[service](out)
+	var decErr *DecodeError
+	if !errors.As(err, &decErr) {
+		t.Fatalf("err = %v, want *DecodeError", err)
+	}
+	if decErr.Format != "json" || decErr.Offset < 0 {
+		t.Errorf("DecodeError = %+v", decErr)
+	}
+	if !strings.Contains(decErr.Excerpt, "WARNING") || !strings.HasPrefix(decErr.Excerpt, "...") {
+		t.Errorf("Excerpt = %q, want the text around WARNING", decErr.Excerpt)
+	}
+
+	_, err = DecodeJSON[service](`{"Name":"a","Status":"Running"}`)
+	if !errors.As(err, &decErr) || !strings.Contains(decErr.Excerpt, "Status") {
+		t.Errorf("type error: err = %v", err)
+	}
+
+	_, err = DecodeJSON[[]service]("not json")
+	if !errors.As(err, &decErr) || decErr.Excerpt != "not json" {
+		t.Errorf("single value error: err = %v", err)
+	}
+}
+
+func TestDecodeCSV(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		want []map[string]string
+	}{
+		{
+			"wmic",
+			"\r\r\nNode,Caption,ProcessId\r\r\nPC,System,4\r\r\nPC,\"svc, host\",812\r\r\n",
+			[]map[string]string{
+				{"Node": "PC", "Caption": "System", "ProcessId": "4"},
+				{"Node": "PC", "Caption": "svc, host", "ProcessId": "812"},
+			},
+		},
+		{
+			"powershell with type line",
+			"#TYPE System.Diagnostics.Process\n\"Name\",\"Id\"\n\"pwsh\",\"100\"",
+			[]map[string]string{{"Name": "pwsh", "Id": "100"}},
+		},
+		{"header only", "A,B\n", []map[string]string{}},
+		{"empty", "", []map[string]string{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := DecodeCSV(tt.in)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got == nil || !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDecodeCSV_Errors(t *testing.T) {
+	_, err := DecodeCSV("A,B\n1,2\n3,4,5\n")
+	var decErr *DecodeError
+	if !errors.As(err, &decErr) {
+		t.Fatalf("err = %v, want *DecodeError", err)
+	}
+	if decErr.Format != "csv" || decErr.Offset != 8 || !strings.Contains(decErr.Excerpt, "3,4,5") {
+		t.Errorf("DecodeError = %+v, want offset 8 near 3,4,5", decErr)
+	}
+}
+
+func TestExcerpt(t *testing.T) {
+	long := []byte(strings.Repeat("a", 50) + "X" + strings.Repeat("b", 50))
+	got := excerpt(long, 50)
+	want := "..." + strings.Repeat("a", excerptRadius) + "X" + strings.Repeat("b", excerptRadius-1) + "..."
+	if got != want {
+		t.Errorf("excerpt = %q, want %q", got, want)
+	}
+	if got := excerpt([]byte("short"), -1); got != "short" {
+		t.Errorf("excerpt(short) = %q", got)
+	}
+}
@@ -82,14 +82,35 @@ func NewDecodingReader(r io.Reader, enc string) (io.Reader, error) {
 	return transform.NewReader(r, e.NewDecoder()), nil
 }
 
-// newAutoDetectReader peeks at the first bytes to detect encoding via BOM.
+// newAutoDetectReader returns a reader that detects the encoding via BOM.
+// Detection is deferred to the first Read, so wrapping a process pipe
+// before the process has started does not block.
 func newAutoDetectReader(r io.Reader) (io.Reader, error) {
+	return &autoDetectReader{src: r}, nil
+}
+
+// autoDetectReader peeks at the first bytes of src on its first Read.
+type autoDetectReader struct {
+	src io.Reader
+	r   io.Reader
+}
+
+func (a *autoDetectReader) Read(p []byte) (int, error) {
+	if a.r == nil {
+		a.r = detectReader(a.src)
+	}
+	return a.r.Read(p)
+}
+
+// detectReader peeks at the first bytes of r and returns a reader that
+// decodes according to the detected BOM.
+func detectReader(r io.Reader) io.Reader {
 	// Read enough bytes for BOM detection.
 	buf := make([]byte, 4)
 	n, err := io.ReadAtLeast(r, buf, 2)
 	if err != nil && err != io.ErrUnexpectedEOF {
 		if n == 0 {
-			return r, nil
+			return r
 		}
 	}
 	peek := buf[:n]
@@ -99,7 +120,7 @@ func newAutoDetectReader(r io.Reader) (io.Reader, error) {
 	combined := io.MultiReader(bytes.NewReader(peek), r)
 
 	if e == nil {
-		return combined, nil
+		return combined
 	}
-	return transform.NewReader(combined, e.NewDecoder()), nil
+	return transform.NewReader(combined, e.NewDecoder())
 }
@@ -148,3 +148,30 @@ func TestResolveEncoding_Aliases(t *testing.T) {
 
 // Suppress unused import warnings.
 var _ = unicode.UTF16
+
+// countingReader records how many times Read was called.
+type countingReader struct {
+	r     io.Reader
+	reads int
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	c.reads++
+	return c.r.Read(p)
+}
+
+func TestNewDecodingReader_AutoDefersRead(t *testing.T) {
+	src := &countingReader{r: bytes.NewReader([]byte{0xFF, 0xFE, 0x41, 0x00})}
+	r, err := NewDecodingReader(src, "auto")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// A process pipe has no data before Start; detection must not block here.
+	if src.reads != 0 {
+		t.Fatalf("auto detection read %d times before first Read", src.reads)
+	}
+	got, _ := io.ReadAll(r)
+	if !bytes.Contains(got, []byte("A")) {
+		t.Errorf("got %q, expected it to contain 'A'", got)
+	}
+}
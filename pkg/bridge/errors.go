@@ -0,0 +1,198 @@
+package bridge
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os/exec"
+	"strings"
+)
+
+// Sentinel errors returned by Execute. Use errors.Is to test for them.
+var (
+	// ErrNotWSL is returned when not running inside WSL.
+	ErrNotWSL = errors.New("gowinbridge: not running in a WSL environment")
+
+	// ErrTimeout is returned when the command was stopped because
+	// CommandConfig.Timeout or the context deadline elapsed. The error
+	// also matches context.DeadlineExceeded.
+	ErrTimeout = errors.New("gowinbridge: command timed out")
+
+	// ErrCanceled is returned when the command was stopped because the
+	// context was canceled. The error also matches context.Canceled.
+	ErrCanceled = errors.New("gowinbridge: command canceled")
+)
+
+// stderrTailLines and stderrTailBytes bound ExitError.StderrTail.
+const (
+	stderrTailLines = 10
+	stderrTailBytes = 2048
+)
+
+// StartError is returned when the command could not be started: it was not
+// found, its arguments could not be prepared, or process creation failed.
+type StartError struct {
+	// Command is the command as given in CommandConfig.
+	Command string
+	// Err is the underlying cause.
+	Err error
+}
+
+func (e *StartError) Error() string {
+	return fmt.Sprintf("failed to start command %q: %v", e.Command, e.Err)
+}
+
+func (e *StartError) Unwrap() error { return e.Err }
+
+// NotFoundError is returned, wrapped in a *StartError, when the command
+// could not be found. It matches exec.ErrNotFound.
+type NotFoundError struct {
+	// Command is the command as given in CommandConfig.
+	Command string
+	// Extensions are the PATHEXT extensions that were tried, if any.
+	Extensions []string
+	// Searched are the directories that were searched.
+	Searched []string
+	// Hint, if set, suggests how to run the command instead.
+	Hint string
+}
+
+func (e *NotFoundError) Error() string {
+	msg := fmt.Sprintf("command %q not found", e.Command)
+	if len(e.Extensions) > 0 {
+		msg += " (tried " + strings.Join(e.Extensions, ", ") + ")"
+	}
+	if len(e.Searched) > 0 {
+		msg += " in " + strings.Join(e.Searched, ", ")
+	}
+	if e.Hint != "" {
+		msg += "; " + e.Hint
+	}
+	return msg
+}
+
+func (e *NotFoundError) Unwrap() error { return exec.ErrNotFound }
+
+// ExitError is returned when the command ran to completion but exited with
+// a non-zero code. The Output returned alongside it is fully populated.
+type ExitError struct {
+	// Command is the command as given in CommandConfig.
+	Command string
+	// Code is the process exit code.
+	Code int
+	// StderrTail holds the last few lines of stderr, for error reporting.
+	// It is empty in interactive mode, where stderr is not captured.
+	StderrTail string
+}
+
+func (e *ExitError) Error() string {
+	msg := fmt.Sprintf("command %q exited with code %d", e.Command, e.Code)
+	if e.StderrTail != "" {
+		msg += ": " + e.StderrTail
+	}
+	return msg
+}
+
+// DecodeError is returned by ExecuteJSON, ExecuteCSV and their Decode
+// counterparts when the command's output could not be parsed.
+type DecodeError struct {
+	// Format is "json" or "csv".
+	Format string
+	// Offset is the byte offset of the failure in the parsed output, after
+	// surrounding whitespace and skipped lines are removed, or -1 if unknown.
+	Offset int64
+	// Excerpt is the output around Offset, or its beginning.
+	Excerpt string
+	// Err is the underlying parse error.
+	Err error
+}
+
+func (e *DecodeError) Error() string {
+	return fmt.Sprintf("failed to decode %s output: %v (near %q)", e.Format, e.Err, e.Excerpt)
+}
+
+func (e *DecodeError) Unwrap() error { return e.Err }
+
+// TerminationReason describes why a command stopped running.
+type TerminationReason int
+
+// TerminationReason values.
+const (
+	// TerminationNone means the command never ran.
+	TerminationNone TerminationReason = iota
+	// TerminationExited means the command exited on its own, with any code.
+	TerminationExited
+	// TerminationTimeout means the command was stopped by a timeout.
+	TerminationTimeout
+	// TerminationCanceled means the command was stopped by cancellation.
+	TerminationCanceled
+)
+
+// String returns a short lowercase name for the reason.
+func (r TerminationReason) String() string {
+	switch r {
+	case TerminationExited:
+		return "exited"
+	case TerminationTimeout:
+		return "timeout"
+	case TerminationCanceled:
+		return "canceled"
+	default:
+		return "none"
+	}
+}
+
+// classifyResult sets output.TerminationReason and turns the outcome of a
+// run into the typed errors above. parent is the caller's context and
+// execCtx the derived context that carries config.Timeout. err is the
+// error from the execution mode, which already carries start failures.
+func classifyResult(parent, execCtx context.Context, config CommandConfig, output Output, err error) (Output, error) {
+	var startErr *StartError
+	if errors.As(err, &startErr) {
+		return output, err
+	}
+
+	switch {
+	case execCtx.Err() != nil && parent.Err() == context.Canceled:
+		output.TerminationReason = TerminationCanceled
+		return output, errors.Join(fmt.Errorf("%w: %w", ErrCanceled, parent.Err()), err)
+	case execCtx.Err() != nil:
+		output.TerminationReason = TerminationTimeout
+		cause := fmt.Errorf("%w: %w", ErrTimeout, context.DeadlineExceeded)
+		if parent.Err() == nil && config.Timeout > 0 {
+			cause = fmt.Errorf("%w after %s: %w", ErrTimeout, config.Timeout, context.DeadlineExceeded)
+		}
+		return output, errors.Join(cause, err)
+	}
+
+	output.TerminationReason = TerminationExited
+	if err != nil {
+		return output, err
+	}
+	if output.ExitCode != 0 {
+		return output, &ExitError{
+			Command:    config.Command,
+			Code:       output.ExitCode,
+			StderrTail: tailLines(output.Stderr, stderrTailLines, stderrTailBytes),
+		}
+	}
+	return output, nil
+}
+
+// tailLines returns at most the last n lines of s, and at most max bytes.
+func tailLines(s string, n, max int) string {
+	s = strings.TrimRight(s, "\r\n")
+	idx := len(s)
+	for i := 0; i < n && idx > 0; i++ {
+		idx = strings.LastIndexByte(s[:idx], '\n')
+		if idx < 0 {
+			idx = 0
+			break
+		}
+	}
+	tail := strings.TrimLeft(s[idx:], "\r\n")
+	if len(tail) > max {
+		tail = tail[len(tail)-max:]
+	}
+	return tail
+}
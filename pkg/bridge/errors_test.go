@@ -0,0 +1,108 @@
+package bridge
+
+import (
+	"context"
+	"errors"
+	"os"
+	"testing"
+	"time"
+)
+
+func TestClassifyResult_NonZeroExit(t *testing.T) {
+	ctx := context.Background()
+	config := CommandConfig{Command: "cmd.exe"}
+	out, err := classifyResult(ctx, ctx, config, Output{ExitCode: 3, Stderr: "a\nb\nboom\n"}, nil)
+
+	var exitErr *ExitError
+	if !errors.As(err, &exitErr) {
+		t.Fatalf("err = %v, want *ExitError", err)
+	}
+	if exitErr.Code != 3 || exitErr.Command != "cmd.exe" {
+		t.Errorf("ExitError = %+v, want code 3 for cmd.exe", exitErr)
+	}
+	if exitErr.StderrTail != "a\nb\nboom" {
+		t.Errorf("StderrTail = %q", exitErr.StderrTail)
+	}
+	if out.TerminationReason != TerminationExited {
+		t.Errorf("TerminationReason = %s, want exited", out.TerminationReason)
+	}
+}
+
+func TestClassifyResult_Success(t *testing.T) {
+	ctx := context.Background()
+	out, err := classifyResult(ctx, ctx, CommandConfig{}, Output{}, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if out.TerminationReason != TerminationExited {
+		t.Errorf("TerminationReason = %s, want exited", out.TerminationReason)
+	}
+}
+
+func TestClassifyResult_Timeout(t *testing.T) {
+	parent := context.Background()
+	execCtx, cancel := context.WithTimeout(parent, time.Nanosecond)
+	defer cancel()
+	<-execCtx.Done()
+
+	config := CommandConfig{Command: "ping.exe", Timeout: time.Nanosecond}
+	out, err := classifyResult(parent, execCtx, config, Output{ExitCode: 1}, nil)
+
+	if !errors.Is(err, ErrTimeout) || !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("err = %v, want ErrTimeout wrapping context.DeadlineExceeded", err)
+	}
+	var exitErr *ExitError
+	if errors.As(err, &exitErr) {
+		t.Error("timed-out command reported as *ExitError")
+	}
+	if out.TerminationReason != TerminationTimeout {
+		t.Errorf("TerminationReason = %s, want timeout", out.TerminationReason)
+	}
+}
+
+func TestClassifyResult_Canceled(t *testing.T) {
+	parent, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	out, err := classifyResult(parent, parent, CommandConfig{}, Output{ExitCode: -1}, nil)
+	if !errors.Is(err, ErrCanceled) || !errors.Is(err, context.Canceled) {
+		t.Errorf("err = %v, want ErrCanceled wrapping context.Canceled", err)
+	}
+	if out.TerminationReason != TerminationCanceled {
+		t.Errorf("TerminationReason = %s, want canceled", out.TerminationReason)
+	}
+}
+
+func TestClassifyResult_StartError(t *testing.T) {
+	ctx := context.Background()
+	startErr := &StartError{Command: "nope.exe", Err: os.ErrNotExist}
+	out, err := classifyResult(ctx, ctx, CommandConfig{}, Output{}, startErr)
+
+	var got *StartError
+	if !errors.As(err, &got) || !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("err = %v, want *StartError wrapping os.ErrNotExist", err)
+	}
+	if out.TerminationReason != TerminationNone {
+		t.Errorf("TerminationReason = %s, want none", out.TerminationReason)
+	}
+}
+
+func TestTailLines(t *testing.T) {
+	tests := []struct {
+		in   string
+		n    int
+		max  int
+		want string
+	}{
+		{"", 3, 100, ""},
+		{"one\n", 3, 100, "one"},
+		{"1\n2\n3\n4\n", 2, 100, "3\n4"},
+		{"1\r\n2\r\n3\r\n", 2, 100, "2\r\n3"},
+		{"abcdef", 1, 3, "def"},
+	}
+	for _, tt := range tests {
+		if got := tailLines(tt.in, tt.n, tt.max); got != tt.want {
+			t.Errorf("tailLines(%q, %d, %d) = %q, want %q", tt.in, tt.n, tt.max, got, tt.want)
+		}
+	}
+}
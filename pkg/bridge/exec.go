@@ -1,13 +1,12 @@
 package bridge
 
 import (
-	"bufio"
 	"context"
+	"errors"
 	"fmt"
 	"io"
 	"os"
 	"os/exec"
-	"strings"
 	"sync"
 	"time"
 
@@ -25,95 +24,132 @@ var (
 func validateWSL() error {
 	wslCheckOnce.Do(func() {
 		if !wsl.IsWSL() {
-			wslCheckErr = fmt.Errorf("gowinbridge: not running in a WSL environment")
+			wslCheckErr = ErrNotWSL
 		}
 	})
 	return wslCheckErr
 }
 
-// resolveCommand ensures the command has a .exe extension.
-// If the command does not end in .exe, it attempts to find the .exe variant on PATH.
-func resolveCommand(command string) string {
-	if strings.HasSuffix(strings.ToLower(command), ".exe") {
-		return command
-	}
-
-	// Try appending .exe and check if it exists on PATH.
-	withExe := command + ".exe"
-	if _, err := exec.LookPath(withExe); err == nil {
-		return withExe
-	}
-
-	// Fall back to the original command; let exec handle the error.
-	return command
+// IsTerminal reports whether the given file descriptor is a terminal.
+// Exported for use in CLI auto-detection.
+func IsTerminal(fd int) bool {
+	return term.IsTerminal(fd)
 }
 
-// convertPathArgs translates arguments that look like file paths from Linux to Windows format.
-func convertPathArgs(args []string) ([]string, error) {
-	converted := make([]string, len(args))
-	for i, arg := range args {
-		if looksLikePath(arg) {
-			winPath, err := wsl.ToWindowsPath(arg)
-			if err != nil {
-				return nil, fmt.Errorf("failed to convert argument %q: %w", arg, err)
-			}
-			converted[i] = winPath
-		} else {
-			converted[i] = arg
-		}
-	}
-	return converted, nil
-}
+// terminalRestore holds the pending restore of a terminal put in raw mode
+// by a PTY session, so that it can also be undone from a signal handler.
+var (
+	terminalMu      sync.Mutex
+	terminalRestore func()
+)
 
-// looksLikePath returns true if the string might be a file path.
-func looksLikePath(s string) bool {
-	if s == "" {
-		return false
+// setTerminalRestore registers fn as the pending terminal restore and
+// returns a function that runs it, at most once, and unregisters it.
+func setTerminalRestore(fn func()) func() {
+	var once sync.Once
+	restore := func() { once.Do(fn) }
+	terminalMu.Lock()
+	terminalRestore = restore
+	terminalMu.Unlock()
+	return func() {
+		terminalMu.Lock()
+		terminalRestore = nil
+		terminalMu.Unlock()
+		restore()
 	}
-	// Starts with / or ./ or ../ — likely a Linux path.
-	return strings.HasPrefix(s, "/") ||
-		strings.HasPrefix(s, "./") ||
-		strings.HasPrefix(s, "../")
 }
 
-// IsTerminal reports whether the given file descriptor is a terminal.
-// Exported for use in CLI auto-detection.
-func IsTerminal(fd int) bool {
-	return term.IsTerminal(fd)
+// RestoreTerminal restores the local terminal if a PTY session has put it
+// in raw mode. Call it before os.Exit, which skips deferred restores.
+func RestoreTerminal() {
+	terminalMu.Lock()
+	restore := terminalRestore
+	terminalMu.Unlock()
+	if restore != nil {
+		restore()
+	}
 }
 
 // Execute runs a Windows binary from WSL with full lifecycle management.
 // It uses exec.CommandContext for signal propagation and supports both
-// buffered (Scanner) and interactive (raw copy) stdio modes.
+// buffered (line capture) and interactive (raw copy) stdio modes.
 func Execute(ctx context.Context, config CommandConfig) (Output, error) {
+	cmd, execCtx, cancel, err := buildCommand(ctx, config)
+	if err != nil {
+		return Output{}, err
+	}
+	defer cancel()
+
+	var output Output
+	if config.Interactive {
+		// Interactive mode: direct stdio copy, no buffering.
+		output, err = executeInteractive(cmd, config)
+	} else {
+		// Buffered mode: capture output with optional encoding.
+		output, err = executeBuffered(cmd, config)
+	}
+	return classifyResult(ctx, execCtx, config, output, err)
+}
+
+// buildCommand validates the environment and prepares the exec.Cmd for
+// config: command resolution, path conversion, timeout, working directory
+// and environment. It returns the context the command runs under, which
+// carries config.Timeout, and a cancel func that releases it.
+func buildCommand(ctx context.Context, config CommandConfig) (*command, context.Context, context.CancelFunc, error) {
 	// Validate WSL environment (fail fast).
 	if err := validateWSL(); err != nil {
-		return Output{}, err
+		return nil, nil, nil, err
 	}
 
-	// Resolve the command to its .exe variant if needed.
-	resolvedCmd := resolveCommand(config.Command)
+	// Resolve the command through PATHEXT, and run scripts in their host.
+	resolved, err := resolveCommand(config.Command, config.WorkDir)
+	if err != nil {
+		return nil, nil, nil, &StartError{Command: config.Command, Err: err}
+	}
+	resolvedCmd := resolved.Name
+	if resolved.Path != "" {
+		resolvedCmd = resolved.Path
+	}
+	host, hostArgs, err := scriptHost(resolved, config)
+	if err != nil {
+		return nil, nil, nil, &StartError{Command: config.Command, Err: err}
+	}
+	if host != "" {
+		resolvedCmd = host
+	}
 
 	// Optionally convert path-like arguments.
 	args := config.Args
-	if config.ConvertPaths {
-		var err error
-		args, err = convertPathArgs(args)
+	if config.ConvertPaths || len(config.ArgPolicies) > 0 {
+		args, err = newArgTranslator(resolved.Name, config).convertPathArgs(args)
 		if err != nil {
-			return Output{}, fmt.Errorf("path conversion failed: %w", err)
+			return nil, nil, nil, &StartError{Command: config.Command, Err: fmt.Errorf("path conversion failed: %w", err)}
 		}
 	}
 
+	// Quote arguments so that the Windows program parses them back unchanged.
+	// The cmd.exe line of a batch file is ours, so its escaping is too.
+	if isBatchFile(resolved.Name) {
+		args = batchArgs(hostArgs, args)
+	} else {
+		rule := config.Quoting
+		if rule == QuoteAuto {
+			rule = quoteRuleFor(resolvedCmd)
+		}
+		args = interopArgs(append(hostArgs, args...), rule)
+	}
+
 	// Apply timeout if configured.
-	execCtx := ctx
+	execCtx, cancel := context.WithCancel(ctx)
 	if config.Timeout > 0 {
-		var cancel context.CancelFunc
+		cancel()
 		execCtx, cancel = context.WithTimeout(ctx, config.Timeout)
-		defer cancel()
 	}
 
-	// Build the command.
-	cmd := exec.CommandContext(execCtx, resolvedCmd, args...)
+	// Build the command; cancellation and timeout go through the
+	// termination policy rather than an immediate kill.
+	cmd := &command{Cmd: exec.CommandContext(execCtx, resolvedCmd, args...)}
+	cmd.term = installTermination(cmd.Cmd, config.Termination)
 
 	// Set working directory.
 	if config.WorkDir != "" {
@@ -123,18 +159,21 @@ func Execute(ctx context.Context, config CommandConfig) (Output, error) {
 	// Prepare environment.
 	cmd.Env = PrepareEnv(config)
 
-	// Interactive mode: direct stdio copy, no buffering.
-	if config.Interactive {
-		return executeInteractive(cmd, config)
-	}
-
-	// Buffered mode: capture output with optional encoding.
-	return executeBuffered(cmd, config)
+	return cmd, execCtx, cancel, nil
 }
 
 // executeInteractive runs the command with direct stdin/stdout/stderr piping.
-// This supports REPLs, TUI apps, and progress bars.
-func executeInteractive(cmd *exec.Cmd, config CommandConfig) (Output, error) {
+// This supports REPLs, TUI apps, and progress bars. With config.PTY the
+// command is attached to a pseudo-terminal instead.
+func executeInteractive(cmd *command, config CommandConfig) (Output, error) {
+	if config.PTY {
+		in := config.Stdin
+		if in == nil {
+			in = os.Stdin
+		}
+		return executePTY(cmd, config, in, os.Stdout)
+	}
+
 	cmd.Stdout = os.Stdout
 	cmd.Stderr = os.Stderr
 
@@ -147,7 +186,7 @@ func executeInteractive(cmd *exec.Cmd, config CommandConfig) (Output, error) {
 	start := time.Now()
 
 	if err := cmd.Start(); err != nil {
-		return Output{}, fmt.Errorf("failed to start command %q: %w", config.Command, err)
+		return Output{}, &StartError{Command: config.Command, Err: err}
 	}
 
 	waitErr := cmd.Wait()
@@ -167,22 +206,22 @@ func executeInteractive(cmd *exec.Cmd, config CommandConfig) (Output, error) {
 }
 
 // executeBuffered runs the command with buffered stdio capture and optional encoding.
-func executeBuffered(cmd *exec.Cmd, config CommandConfig) (Output, error) {
+func executeBuffered(cmd *command, config CommandConfig) (Output, error) {
 	// Set up pipes.
 	stdoutPipe, err := cmd.StdoutPipe()
 	if err != nil {
-		return Output{}, fmt.Errorf("failed to create stdout pipe: %w", err)
+		return Output{}, &StartError{Command: config.Command, Err: fmt.Errorf("failed to create stdout pipe: %w", err)}
 	}
 	stderrPipe, err := cmd.StderrPipe()
 	if err != nil {
-		return Output{}, fmt.Errorf("failed to create stderr pipe: %w", err)
+		return Output{}, &StartError{Command: config.Command, Err: fmt.Errorf("failed to create stderr pipe: %w", err)}
 	}
 
 	// If stdin is provided in non-interactive mode, pipe it.
 	if config.Stdin != nil {
 		stdinPipe, err := cmd.StdinPipe()
 		if err != nil {
-			return Output{}, fmt.Errorf("failed to create stdin pipe: %w", err)
+			return Output{}, &StartError{Command: config.Command, Err: fmt.Errorf("failed to create stdin pipe: %w", err)}
 		}
 		go func() {
 			defer stdinPipe.Close()
@@ -190,51 +229,39 @@ func executeBuffered(cmd *exec.Cmd, config CommandConfig) (Output, error) {
 		}()
 	}
 
-	// Wrap pipes in encoding decoder if specified.
-	var stdoutReader, stderrReader io.Reader
-	stdoutReader = stdoutPipe
-	stderrReader = stderrPipe
-
-	if config.Encoding != "" {
-		stdoutReader, err = NewDecodingReader(stdoutPipe, config.Encoding)
-		if err != nil {
-			return Output{}, fmt.Errorf("failed to create stdout decoder: %w", err)
-		}
-		stderrReader, err = NewDecodingReader(stderrPipe, config.Encoding)
-		if err != nil {
-			return Output{}, fmt.Errorf("failed to create stderr decoder: %w", err)
-		}
+	// Prepare decoding, raw recording and spilling for each stream.
+	stdoutCap, err := newStreamCapture(StreamStdout, stdoutPipe, config)
+	if err != nil {
+		return Output{}, &StartError{Command: config.Command, Err: err}
+	}
+	stderrCap, err := newStreamCapture(StreamStderr, stderrPipe, config)
+	if err != nil {
+		stdoutCap.discard()
+		return Output{}, &StartError{Command: config.Command, Err: err}
 	}
 
 	start := time.Now()
 
 	if err := cmd.Start(); err != nil {
-		return Output{}, fmt.Errorf("failed to start command %q: %w", config.Command, err)
+		stdoutCap.discard()
+		stderrCap.discard()
+		return Output{}, &StartError{Command: config.Command, Err: err}
 	}
 
 	// Stream stdout and stderr concurrently.
-	var stdoutBuf, stderrBuf strings.Builder
+	sink := newOutputSink(config)
+	var stdoutErr, stderrErr error
 	var wg sync.WaitGroup
 	wg.Add(2)
 
 	go func() {
 		defer wg.Done()
-		scanner := bufio.NewScanner(stdoutReader)
-		for scanner.Scan() {
-			line := scanner.Text()
-			stdoutBuf.WriteString(line)
-			stdoutBuf.WriteString("\n")
-		}
+		stdoutErr = stdoutCap.run(sink)
 	}()
 
 	go func() {
 		defer wg.Done()
-		scanner := bufio.NewScanner(stderrReader)
-		for scanner.Scan() {
-			line := scanner.Text()
-			stderrBuf.WriteString(line)
-			stderrBuf.WriteString("\n")
-		}
+		stderrErr = stderrCap.run(sink)
 	}()
 
 	// Wait for streaming goroutines to finish reading.
@@ -245,10 +272,21 @@ func executeBuffered(cmd *exec.Cmd, config CommandConfig) (Output, error) {
 	duration := time.Since(start)
 
 	output := Output{
-		Stdout:   strings.TrimRight(stdoutBuf.String(), "\n"),
-		Stderr:   strings.TrimRight(stderrBuf.String(), "\n"),
+		Stdout:   normalizeNewlines(stdoutCap.text.String(), config.Newlines),
+		Stderr:   normalizeNewlines(stderrCap.text.String(), config.Newlines),
 		Duration: duration,
 	}
+	if config.Combined {
+		output.Combined = sink.recorded()
+	}
+	if config.RawOutput {
+		output.StdoutRaw = stdoutCap.raw.Bytes()
+		output.StderrRaw = stderrCap.raw.Bytes()
+	}
+
+	var stdoutSpillErr, stderrSpillErr error
+	output.StdoutTruncation, stdoutSpillErr = stdoutCap.truncation()
+	output.StderrTruncation, stderrSpillErr = stderrCap.truncation()
 
 	if waitErr != nil {
 		if exitErr, ok := waitErr.(*exec.ExitError); ok {
@@ -258,6 +296,11 @@ func executeBuffered(cmd *exec.Cmd, config CommandConfig) (Output, error) {
 		}
 	}
 
+	// Surface read, decoding or spill failures; the output gathered so far is kept.
+	if err := errors.Join(stdoutErr, stderrErr, stdoutSpillErr, stderrSpillErr); err != nil {
+		return output, fmt.Errorf("output capture failed: %w", err)
+	}
+
 	return output, nil
 }
 
@@ -0,0 +1,207 @@
+package bridge
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/sibikrish3000/gowinbridge/pkg/wslpath"
+)
+
+// locationSuffix splits a path found in output from a trailing source
+// location: "main.c(12)", "main.c(12,5)", "main.go:12" or "main.go:12:5".
+var locationSuffix = regexp.MustCompile(`^(.*?)(\(\d+(?:,\d+)*\)|:\d+(?::\d+)?)?$`)
+
+// RewriteWindowsPaths returns text with the Windows paths in it replaced
+// by the Linux paths they refer to: drive paths such as C:\src\main.c,
+// C:/src/main.c or \\?\C:\src\main.c become /mnt/c/src/main.c,
+// \\wsl.localhost\<distro>\ or \\wsl$\<distro>\ UNC paths of this distro
+// become /, and paths on network shares map to where the share is
+// mounted. Paths with no Linux equivalent, such as unmounted shares, are
+// left alone. Source locations after a path, as in "main.c(12,5):" or
+// "main.go:12:5:", are kept.
+//
+// A path ends at whitespace or a quote, except that it extends across a
+// space when a later word on the same line continues it with another
+// separator, as in C:\Program Files (x86)\Windows Kits\10\stdio.h(12),
+// and it has not already ended with a source location or punctuation. A
+// path is always rewritten whole or not at all.
+func RewriteWindowsPaths(text string) string {
+	if !strings.Contains(text, `:\`) && !strings.Contains(text, `:/`) && !strings.Contains(text, `\\`) {
+		return text
+	}
+
+	var b strings.Builder
+	last := 0
+	for i := 0; i < len(text); i++ {
+		head := windowsPathHead(text, i)
+		if head == 0 {
+			continue
+		}
+		end := pathEnd(text, i, head)
+		path, tail := splitPathTail(text[i:end], head)
+		linuxPath, err := wslpath.ToLinuxPath(path)
+		if err != nil {
+			i = end - 1
+			continue
+		}
+		b.WriteString(text[last:i])
+		b.WriteString(linuxPath)
+		b.WriteString(tail)
+		last = end
+		i = end - 1
+	}
+	if last == 0 {
+		return text
+	}
+	b.WriteString(text[last:])
+	return b.String()
+}
+
+// pathEnd returns the end of the path starting at text[i], whose prefix
+// has length head. Spaces are crossed as long as continuedAt finds the
+// path going on past them.
+func pathEnd(text string, i, head int) int {
+	sep := text[i+head-1]
+	end := wordEnd(text, i+head)
+	for end < len(text) && text[end] == ' ' {
+		if _, tail := splitPathTail(text[i:end], head); tail != "" {
+			break
+		}
+		next := continuedAt(text, end, sep)
+		if next == 0 {
+			break
+		}
+		end = next
+	}
+	return end
+}
+
+// continuedAt looks at the words after the space at text[i] and returns
+// the end of the first one containing sep, if the words before it could
+// be part of a path, or 0. A word that ends the line, starts another
+// Windows path, or ends in a source location or punctuation stops the
+// search.
+func continuedAt(text string, i int, sep byte) int {
+	for i < len(text) && text[i] == ' ' {
+		for i < len(text) && text[i] == ' ' {
+			i++
+		}
+		if i == len(text) || isPathTerminator(text[i]) || windowsPathHead(text, i) > 0 {
+			return 0
+		}
+		end := wordEnd(text, i)
+		word := text[i:end]
+		if strings.IndexByte(word, sep) >= 0 {
+			return end
+		}
+		if _, tail := splitPathTail(word, 0); tail != "" {
+			return 0
+		}
+		i = end
+	}
+	return 0
+}
+
+// wordEnd returns the index of the first path terminator at or after i.
+func wordEnd(text string, i int) int {
+	for i < len(text) && !isPathTerminator(text[i]) {
+		i++
+	}
+	return i
+}
+
+// windowsPathHead returns the length of the drive ("C:\", "C:/"), WSL
+// ("\\wsl.localhost\Ubuntu\") or network share ("\\server\share\") prefix
+// of a Windows path starting at text[i], possibly in its long-path form
+// ("\\?\C:\", "\\?\UNC\server\share\"), or 0. UNC paths of other distros
+// are not matched, since they have no Linux path here.
+func windowsPathHead(text string, i int) int {
+	s := text[i:]
+	if strings.HasPrefix(s, `\\?\`) || strings.HasPrefix(s, `\\.\`) {
+		rest := s[4:]
+		if n := driveHead(rest); n > 0 {
+			return 4 + n
+		}
+		if len(rest) >= 4 && strings.EqualFold(rest[:4], `UNC\`) {
+			if n := uncHead(`\\` + rest[4:]); n > 0 {
+				return 6 + n
+			}
+		}
+		return 0
+	}
+	if n := driveHead(s); n > 0 {
+		if i > 0 && (isFlagNameChar(text[i-1]) || text[i-1] == '\\' || text[i-1] == '/') {
+			return 0
+		}
+		return n
+	}
+	return uncHead(s)
+}
+
+// driveHead returns 3 if s starts with a drive such as "C:\" or "C:/".
+func driveHead(s string) int {
+	if len(s) >= 3 && isASCIILetter(s[0]) && s[1] == ':' && (s[2] == '\\' || s[2] == '/') {
+		return 3
+	}
+	return 0
+}
+
+// uncHead returns the length of the "\\host\share\" prefix s starts with,
+// or 0.
+func uncHead(s string) int {
+	if !strings.HasPrefix(s, `\\`) {
+		return 0
+	}
+	host := uncSegment(s, 2)
+	if host == 2 || host == len(s) || s[host] != '\\' {
+		return 0
+	}
+	share := uncSegment(s, host+1)
+	if share == host+1 {
+		return 0
+	}
+	wslHost := strings.EqualFold(s[2:host], "wsl.localhost") || s[2:host] == "wsl$"
+	if distro, err := wslpath.DistroName(); wslHost && err == nil && !strings.EqualFold(s[host+1:share], distro) {
+		return 0
+	}
+	if share < len(s) && s[share] == '\\' {
+		share++
+	}
+	return share
+}
+
+// uncSegment returns the end of the UNC path segment starting at s[i].
+func uncSegment(s string, i int) int {
+	for i < len(s) && s[i] != '\\' && !isPathTerminator(s[i]) {
+		i++
+	}
+	return i
+}
+
+// splitPathTail splits p, which starts with a path prefix of length head,
+// into the path itself and the trailing punctuation and source location
+// that follow it.
+func splitPathTail(p string, head int) (string, string) {
+	end := len(p)
+	for end > head {
+		c := p[end-1]
+		if c == '.' || c == ',' || c == ';' || c == ':' ||
+			c == ')' && strings.Count(p[:end], "(") < strings.Count(p[:end], ")") {
+			end--
+			continue
+		}
+		break
+	}
+	m := locationSuffix.FindStringSubmatch(p[head:end])
+	path := p[:head] + m[1]
+	return path, p[len(path):]
+}
+
+// isPathTerminator reports whether c ends a path in output.
+func isPathTerminator(c byte) bool {
+	switch c {
+	case ' ', '\t', '\r', '\n', '"', '\'', '`', '<', '>', '|', '*', '?':
+		return true
+	}
+	return false
+}
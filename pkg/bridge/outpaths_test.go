@@ -0,0 +1,47 @@
+package bridge
+
+import "testing"
+
+func TestRewriteWindowsPaths(t *testing.T) {
+	t.Setenv("WSL_DISTRO_NAME", "Ubuntu")
+
+	tests := []struct {
+		name string
+		in   string
+		want string
+	}{
+		{"msvc error", `C:\src\proj\main.c(12): error C2065`, `/mnt/c/src/proj/main.c(12): error C2065`},
+		{"msvc line and column", `c:\src\main.c(12,5): warning`, `/mnt/c/src/main.c(12,5): warning`},
+		{"gcc style", `D:\work\main.go:12:5: undefined: x`, `/mnt/d/work/main.go:12:5: undefined: x`},
+		{"line only", `C:\a\b.txt:7`, `/mnt/c/a/b.txt:7`},
+		{"unc localhost", `\\wsl.localhost\Ubuntu\home\me\a.c(3): error`, `/home/me/a.c(3): error`},
+		{"unc dollar", `see \\wsl$\ubuntu\etc\hosts.`, `see /etc/hosts.`},
+		{"unc other distro", `\\wsl.localhost\Debian\home\x`, `\\wsl.localhost\Debian\home\x`},
+		{"drive root", `cd C:\`, `cd /mnt/c`},
+		{"quoted", `copied "C:\x\y.txt" to 'E:\z'`, `copied "/mnt/c/x/y.txt" to '/mnt/e/z'`},
+		{"in parentheses", `(at C:\x\main.c(3))`, `(at /mnt/c/x/main.c(3))`},
+		{"sentence", `Wrote C:\out\app.exe, done.`, `Wrote /mnt/c/out/app.exe, done.`},
+		{"several", `C:\a D:\b`, `/mnt/c/a /mnt/d/b`},
+		{"long path", `\\?\C:\src\a.c(3): error`, `/mnt/c/src/a.c(3): error`},
+		{"long unc path", `\\?\UNC\wsl.localhost\Ubuntu\home\x:1`, `/home/x:1`},
+		{"forward slashes", `at C:/Users/me/app.js:3:5`, `at /mnt/c/Users/me/app.js:3:5`},
+		{"url", `see https://example.com/a and file:///C:/x`, `see https://example.com/a and file:///C:/x`},
+		{"device path", `\\.\pipe\docker_engine`, `\\.\pipe\docker_engine`},
+		{"unmounted share", `copy \\nas\backups\x.zip`, `copy \\nas\backups\x.zip`},
+		{"not a drive", `abc:\x and 1C:\y`, `abc:\x and 1C:\y`},
+		{"no paths", `plain text: 12`, `plain text: 12`},
+		{"spaces", `C:\Program Files\x`, `/mnt/c/Program Files/x`},
+		{"spaces before location", `C:\Program Files (x86)\Windows Kits\10\Include\stdio.h(12): error C2143: syntax error`,
+			`/mnt/c/Program Files (x86)/Windows Kits/10/Include/stdio.h(12): error C2143: syntax error`},
+		{"spaces then prose", `Wrote C:\My Files\a.txt and done`, `Wrote /mnt/c/My Files/a.txt and done`},
+		{"space then another path", `copy C:\a b.txt D:\x`, `copy /mnt/c/a b.txt /mnt/d/x`},
+		{"space after location", `C:\x\a.c(3): see b\c.h`, `/mnt/c/x/a.c(3): see b\c.h`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := RewriteWindowsPaths(tt.in); got != tt.want {
+				t.Errorf("RewriteWindowsPaths(%q) = %q, want %q", tt.in, got, tt.want)
+			}
+		})
+	}
+}
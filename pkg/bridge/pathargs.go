@@ -0,0 +1,360 @@
+package bridge
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/sibikrish3000/gowinbridge/pkg/wslpath"
+)
+
+// PathRules tunes how ConvertPaths finds Linux paths in arguments.
+//
+// Besides plain paths (/x, ./x, ../x, ~/x), paths are found in flag values
+// (--out=/x, /out:./x, /p:OutDir=./bin), after single-letter flags
+// (-I/usr/include), in response files (@./args.rsp) and after path-taking
+// switches of known tools (cl.exe /Fo./obj/). A bare single-segment
+// argument such as /c or /Q is taken to be a Windows switch unless it
+// exists on disk, like /tmp.
+type PathRules struct {
+	// OnlyExisting translates a path only if it exists on disk. For flag
+	// values, which are often files yet to be created (--out=./new.txt),
+	// an existing parent directory suffices.
+	OnlyExisting bool
+
+	// Switches are extra arguments that are never translated, such as
+	// "/run", in addition to the built-in lists for cmd.exe, msbuild and
+	// cl.exe. Matching ignores case.
+	Switches []string
+
+	// ResolveSymlinks follows symlinks before translating, so that a link
+	// into a drive mount, such as ~/proj → /mnt/c/work/proj, becomes a
+	// drive path rather than a \\wsl.localhost one.
+	ResolveSymlinks bool
+
+	// LongPaths prefixes translated paths longer than MAX_PATH with \\?\,
+	// for programs that support long paths.
+	LongPaths bool
+}
+
+// ArgPolicy selects how one argument is translated, overriding the
+// detection of ConvertPaths.
+type ArgPolicy int
+
+// ArgPolicy values.
+const (
+	// ArgAuto translates the argument if ConvertPaths is set and it
+	// looks like a path.
+	ArgAuto ArgPolicy = iota
+	// ArgPath translates the whole argument as one path, even without
+	// ConvertPaths. Bare names such as "out.txt" are resolved against
+	// WorkDir.
+	ArgPath
+	// ArgPathList translates a colon-separated list of Linux paths into a
+	// semicolon-separated list of Windows paths, like PATH.
+	ArgPathList
+	// ArgLiteral passes the argument unchanged.
+	ArgLiteral
+)
+
+// String returns the policy name: "auto", "path", "path-list" or "literal".
+func (p ArgPolicy) String() string {
+	switch p {
+	case ArgAuto:
+		return "auto"
+	case ArgPath:
+		return "path"
+	case ArgPathList:
+		return "path-list"
+	case ArgLiteral:
+		return "literal"
+	default:
+		return fmt.Sprintf("ArgPolicy(%d)", int(p))
+	}
+}
+
+// toolSwitches describes the switches of a Windows program that matter
+// to path detection.
+type toolSwitches struct {
+	// plain are switches that are never paths.
+	plain []string
+	// pathPrefix are switches directly followed by a path.
+	pathPrefix []string
+}
+
+// knownTools maps lowercase program names, without extension, to their
+// switches. Switches are listed with "/" and match "-" too.
+var knownTools = map[string]toolSwitches{
+	"cmd": {
+		plain: []string{"/c", "/k", "/s", "/q", "/d", "/a", "/u", "/r"},
+	},
+	"msbuild": {
+		plain: []string{"/m", "/r", "/restore", "/nologo", "/noautorsp", "/bl", "/ds", "/detailedsummary", "/nr"},
+	},
+	"cl": {
+		plain:      []string{"/c", "/nologo", "/ehsc", "/zi", "/md", "/mt", "/mdd", "/mtd", "/o1", "/o2", "/od", "/w4", "/wx", "/e", "/ep", "/p"},
+		pathPrefix: []string{"/fo", "/fe", "/fd", "/fa", "/fp", "/fi", "/fm", "/fr", "/i", "/tc", "/tp"},
+	},
+}
+
+// argTranslator translates the Linux paths in the arguments of one
+// command.
+type argTranslator struct {
+	rules    PathRules
+	workDir  string
+	tool     toolSwitches
+	auto     bool
+	policies []ArgPolicy
+}
+
+// newArgTranslator returns a translator for the arguments of command.
+func newArgTranslator(command string, config CommandConfig) *argTranslator {
+	name := strings.ToLower(command[strings.LastIndexAny(command, `/\`)+1:])
+	if ext := windowsExt(name); ext != "" {
+		name = strings.TrimSuffix(name, ext)
+	}
+	return &argTranslator{
+		rules:    config.PathRules,
+		workDir:  config.WorkDir,
+		tool:     knownTools[name],
+		auto:     config.ConvertPaths,
+		policies: config.ArgPolicies,
+	}
+}
+
+// convertPathArgs translates the Linux paths found in args to Windows
+// paths under the rules and per-argument policies of t.
+func (t *argTranslator) convertPathArgs(args []string) ([]string, error) {
+	converted := make([]string, len(args))
+	for i, arg := range args {
+		policy := ArgAuto
+		if i < len(t.policies) {
+			policy = t.policies[i]
+		}
+
+		var out string
+		var err error
+		switch {
+		case policy == ArgPath:
+			out, err = t.path(arg)
+		case policy == ArgPathList:
+			out, err = t.pathList(arg)
+		case policy == ArgAuto && t.auto:
+			out, err = t.translate(arg)
+		default:
+			out = arg
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to convert argument %q: %w", arg, err)
+		}
+		converted[i] = out
+	}
+	return converted, nil
+}
+
+// translate returns arg with the Linux path it contains, if any, replaced
+// by a Windows path.
+func (t *argTranslator) translate(arg string) (string, error) {
+	if arg == "" || t.isSwitch(arg) {
+		return arg, nil
+	}
+	if n := t.pathSwitchLen(arg); n > 0 {
+		return t.attach(arg[:n], arg[n:])
+	}
+
+	switch arg[0] {
+	case '@':
+		return t.attach("@", arg[1:])
+	case '-', '/':
+		if n := flagValueIndex(arg); n > 0 {
+			return t.value(arg[:n], arg[n:])
+		}
+		if arg[0] == '-' {
+			// -I/usr/include, -o./out
+			if len(arg) > 2 && isASCIILetter(arg[1]) && looksLikePath(arg[2:]) {
+				return t.attach(arg[:2], arg[2:])
+			}
+			return arg, nil
+		}
+	}
+	if isSingleSegment(arg) && !exists(t.expand(arg)) {
+		// /c, /Q, /nologo: a Windows switch rather than a path.
+		return arg, nil
+	}
+	return t.attach("", arg)
+}
+
+// value translates the value of a flag: a path, or a KEY=path property.
+func (t *argTranslator) value(prefix, v string) (string, error) {
+	if looksLikePath(v) {
+		return t.attach(prefix, v)
+	}
+	if n := keyValueIndex(v); n > 0 && looksLikePath(v[n:]) {
+		return t.attach(prefix+v[:n], v[n:])
+	}
+	return prefix + v, nil
+}
+
+// attach translates p if it looks like a path and returns it after prefix.
+func (t *argTranslator) attach(prefix, p string) (string, error) {
+	if !looksLikePath(p) {
+		return prefix + p, nil
+	}
+	linuxPath := t.expand(p)
+	if t.rules.OnlyExisting && !exists(linuxPath) && (prefix == "" || !exists(filepath.Dir(linuxPath))) {
+		return prefix + p, nil
+	}
+	winPath, err := t.path(p)
+	if err != nil {
+		return "", err
+	}
+	return prefix + winPath, nil
+}
+
+// path translates p as a path, whatever it looks like. A trailing slash
+// is kept as a trailing backslash.
+func (t *argTranslator) path(p string) (string, error) {
+	if p == "" {
+		return p, nil
+	}
+	var mode wslpath.Mode
+	if t.rules.ResolveSymlinks {
+		mode |= wslpath.ResolveSymlinks
+	}
+	winPath, err := wslpath.ToWindowsPathMode(t.expand(p), mode)
+	if err != nil {
+		return "", err
+	}
+	if t.rules.LongPaths {
+		winPath = wslpath.LongPath(winPath)
+	}
+	if strings.HasSuffix(p, "/") && !strings.HasSuffix(winPath, `\`) {
+		winPath += `\`
+	}
+	return winPath, nil
+}
+
+// pathList translates each path of the colon-separated list and joins
+// them with semicolons. Empty entries are kept.
+func (t *argTranslator) pathList(list string) (string, error) {
+	parts := strings.Split(list, ":")
+	for i, p := range parts {
+		winPath, err := t.path(p)
+		if err != nil {
+			return "", err
+		}
+		parts[i] = winPath
+	}
+	return strings.Join(parts, ";"), nil
+}
+
+// expand returns the absolute Linux path p refers to: ~ is expanded and
+// relative paths are resolved against the working directory.
+func (t *argTranslator) expand(p string) string {
+	if p == "~" || strings.HasPrefix(p, "~/") {
+		if home, err := os.UserHomeDir(); err == nil {
+			p = home + p[1:]
+		}
+	}
+	if !filepath.IsAbs(p) && t.workDir != "" {
+		p = filepath.Join(t.workDir, p)
+	}
+	if abs, err := filepath.Abs(p); err == nil {
+		p = abs
+	}
+	return p
+}
+
+// isSwitch reports whether arg is a switch of the tool, or one of
+// PathRules.Switches, and so never a path.
+func (t *argTranslator) isSwitch(arg string) bool {
+	for _, s := range t.rules.Switches {
+		if strings.EqualFold(arg, s) {
+			return true
+		}
+	}
+	for _, s := range t.tool.plain {
+		if strings.EqualFold(arg, s) || strings.EqualFold(arg, "-"+s[1:]) {
+			return true
+		}
+	}
+	return false
+}
+
+// pathSwitchLen returns the length of the tool's path-taking switch that
+// arg starts with, or 0.
+func (t *argTranslator) pathSwitchLen(arg string) int {
+	if arg[0] != '/' && arg[0] != '-' {
+		return 0
+	}
+	for _, s := range t.tool.pathPrefix {
+		if len(arg) > len(s) && strings.EqualFold(arg[1:len(s)], s[1:]) {
+			return len(s)
+		}
+	}
+	return 0
+}
+
+// looksLikePath returns true if the string might be a Linux file path.
+func looksLikePath(s string) bool {
+	if s == "" {
+		return false
+	}
+	// Starts with / or ./ or ../ or ~ — likely a Linux path.
+	return strings.HasPrefix(s, "/") ||
+		strings.HasPrefix(s, "./") ||
+		strings.HasPrefix(s, "../") ||
+		s == "~" ||
+		strings.HasPrefix(s, "~/")
+}
+
+// flagValueIndex returns the index just past the ':' or '=' that ends the
+// name of a flag such as --out=, /out: or /p:, or 0 if arg has no value.
+func flagValueIndex(arg string) int {
+	i := 0
+	for i < len(arg) && (arg[i] == '-' || (i == 0 && arg[i] == '/')) {
+		i++
+	}
+	start := i
+	for i < len(arg) && isFlagNameChar(arg[i]) {
+		i++
+	}
+	if i == start || i == len(arg) || (arg[i] != '=' && arg[i] != ':') {
+		return 0
+	}
+	return i + 1
+}
+
+// keyValueIndex returns the index just past the '=' of a KEY=value
+// property, or 0.
+func keyValueIndex(s string) int {
+	i := 0
+	for i < len(s) && isFlagNameChar(s[i]) {
+		i++
+	}
+	if i == 0 || i == len(s) || s[i] != '=' {
+		return 0
+	}
+	return i + 1
+}
+
+// isSingleSegment reports whether arg is "/" followed by a single path
+// segment, which may just as well be a Windows switch.
+func isSingleSegment(arg string) bool {
+	return len(arg) > 1 && arg[0] == '/' && !strings.Contains(arg[1:], "/")
+}
+
+// exists reports whether p exists on disk.
+func exists(p string) bool {
+	_, err := os.Stat(p)
+	return err == nil
+}
+
+func isFlagNameChar(c byte) bool {
+	return isASCIILetter(c) || c >= '0' && c <= '9' || c == '-' || c == '_' || c == '.'
+}
+
+func isASCIILetter(c byte) bool {
+	return c|0x20 >= 'a' && c|0x20 <= 'z'
+}
@@ -0,0 +1,234 @@
+package bridge
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/sibikrish3000/gowinbridge/pkg/wslpath"
+)
+
+// win returns the Windows translation of the Linux path p.
+func win(t *testing.T, p string) string {
+	t.Helper()
+	w, err := wslpath.ToWindowsPath(p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return w
+}
+
+func TestLooksLikePath(t *testing.T) {
+	tests := []struct {
+		input string
+		want  bool
+	}{
+		{"/home/user/file.txt", true},
+		{"./relative/file", true},
+		{"../parent/file", true},
+		{"~", true},
+		{"~/file", true},
+		{"~user", false},
+		{"just-a-flag", false},
+		{"-v", false},
+		{"", false},
+		{"C:\\Windows", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			if got := looksLikePath(tt.input); got != tt.want {
+				t.Errorf("looksLikePath(%q) = %v, want %v", tt.input, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestArgTranslator(t *testing.T) {
+	t.Setenv("WSL_DISTRO_NAME", "Ubuntu")
+	work := t.TempDir()
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	if err := os.Mkdir(filepath.Join(work, "obj"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		command string
+		arg     string
+		want    string
+	}{
+		{"absolute path", "tool.exe", "/home/me/x", win(t, "/home/me/x")},
+		{"relative to work dir", "tool.exe", "./src/a.c", win(t, filepath.Join(work, "src/a.c"))},
+		{"parent dir", "tool.exe", "../a", win(t, filepath.Join(filepath.Dir(work), "a"))},
+		{"tilde", "tool.exe", "~/file", win(t, filepath.Join(home, "file"))},
+		{"bare tilde", "tool.exe", "~", win(t, home)},
+		{"trailing slash kept", "tool.exe", "./obj/", win(t, filepath.Join(work, "obj")) + `\`},
+		{"long flag with =", "tool.exe", "--out=/home/me/x", "--out=" + win(t, "/home/me/x")},
+		{"long flag with relative value", "tool.exe", "--out=./build", "--out=" + win(t, filepath.Join(work, "build"))},
+		{"short flag attached", "gcc.exe", "-I/usr/include", "-I" + win(t, "/usr/include")},
+		{"short flag attached relative", "gcc.exe", "-o./out", "-o" + win(t, filepath.Join(work, "out"))},
+		{"response file", "tool.exe", "@./response.rsp", "@" + win(t, filepath.Join(work, "response.rsp"))},
+		{"colon switch", "link.exe", "/out:./app.exe", "/out:" + win(t, filepath.Join(work, "app.exe"))},
+		{"msbuild property", "msbuild.exe", "/p:OutDir=./bin/", "/p:OutDir=" + win(t, filepath.Join(work, "bin")) + `\`},
+		{"cl.exe path switch", "cl.exe", "/Fo./obj/", "/Fo" + win(t, filepath.Join(work, "obj")) + `\`},
+		{"cl.exe dash path switch", "cl.exe", "-Fe./app.exe", "-Fe" + win(t, filepath.Join(work, "app.exe"))},
+		{"cl.exe include", "CL.EXE", "/I/usr/include", "/I" + win(t, "/usr/include")},
+		{"cmd switch", "cmd.exe", "/c", "/c"},
+		{"cmd switch upper", "cmd.exe", "/Q", "/Q"},
+		{"cmd colon switch", "cmd.exe", "/e:on", "/e:on"},
+		{"unknown single segment switch", "robocopy.exe", "/MIR", "/MIR"},
+		{"existing root dir", "tool.exe", "/tmp", win(t, "/tmp")},
+		{"plain word", "tool.exe", "hello", "hello"},
+		{"negative number", "tool.exe", "-1", "-1"},
+		{"flag without path", "tool.exe", "--level=3", "--level=3"},
+		{"windows value", "tool.exe", `--out=C:\x`, `--out=C:\x`},
+		{"url value", "tool.exe", "--url=https://example.com/a", "--url=https://example.com/a"},
+		{"short flag without path", "tool.exe", "-v", "-v"},
+		{"empty", "tool.exe", "", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tr := newArgTranslator(tt.command, CommandConfig{WorkDir: work})
+			got, err := tr.translate(tt.arg)
+			if err != nil {
+				t.Fatalf("translate(%q) error: %v", tt.arg, err)
+			}
+			if got != tt.want {
+				t.Errorf("translate(%q) = %q, want %q", tt.arg, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestArgTranslator_OnlyExisting(t *testing.T) {
+	t.Setenv("WSL_DISTRO_NAME", "Ubuntu")
+	work := t.TempDir()
+	if err := os.WriteFile(filepath.Join(work, "in.txt"), nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	tr := newArgTranslator("tool.exe", CommandConfig{
+		WorkDir:   work,
+		PathRules: PathRules{OnlyExisting: true},
+	})
+
+	tests := []struct {
+		arg  string
+		want string
+	}{
+		{"./in.txt", win(t, filepath.Join(work, "in.txt"))},
+		{"--out=./new.txt", "--out=" + win(t, filepath.Join(work, "new.txt"))},
+		{"/^foo/", "/^foo/"},
+		{"./missing/dir/x", "./missing/dir/x"},
+	}
+	for _, tt := range tests {
+		if got, err := tr.translate(tt.arg); err != nil || got != tt.want {
+			t.Errorf("translate(%q) = %q, %v; want %q", tt.arg, got, err, tt.want)
+		}
+	}
+}
+
+func TestArgTranslator_ExtraSwitches(t *testing.T) {
+	tr := newArgTranslator("tool.exe", CommandConfig{PathRules: PathRules{Switches: []string{"/TMP"}}})
+	if got, _ := tr.translate("/tmp"); got != "/tmp" {
+		t.Errorf("translate(/tmp) = %q, want it left as a switch", got)
+	}
+}
+
+func TestConvertPathArgs(t *testing.T) {
+	t.Setenv("WSL_DISTRO_NAME", "Ubuntu")
+	tr := newArgTranslator("cmd.exe", CommandConfig{ConvertPaths: true})
+	got, err := tr.convertPathArgs([]string{"/c", "type", "/etc/hosts"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"/c", "type", win(t, "/etc/hosts")}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("arg %d = %q, want %q", i, got[i], want[i])
+		}
+	}
+}
+
+func TestConvertPathArgs_Policies(t *testing.T) {
+	t.Setenv("WSL_DISTRO_NAME", "Ubuntu")
+	work := t.TempDir()
+	args := []string{"/^foo/", "out.txt", "./a:/usr/lib", "/etc/hosts", "./b"}
+	policies := []ArgPolicy{ArgLiteral, ArgPath, ArgPathList}
+
+	tests := []struct {
+		name         string
+		convertPaths bool
+		want         []string
+	}{
+		{"with ConvertPaths", true, []string{
+			"/^foo/",
+			win(t, filepath.Join(work, "out.txt")),
+			win(t, filepath.Join(work, "a")) + ";" + win(t, "/usr/lib"),
+			win(t, "/etc/hosts"),
+			win(t, filepath.Join(work, "b")),
+		}},
+		{"without ConvertPaths", false, []string{
+			"/^foo/",
+			win(t, filepath.Join(work, "out.txt")),
+			win(t, filepath.Join(work, "a")) + ";" + win(t, "/usr/lib"),
+			"/etc/hosts",
+			"./b",
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tr := newArgTranslator("grep.exe", CommandConfig{
+				WorkDir:      work,
+				ConvertPaths: tt.convertPaths,
+				ArgPolicies:  policies,
+			})
+			got, err := tr.convertPathArgs(args)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("convertPathArgs = %q, want %q", got, tt.want)
+			}
+		})
+	}
+
+	// Extra policies beyond Args are ignored.
+	tr := newArgTranslator("tool.exe", CommandConfig{ArgPolicies: []ArgPolicy{ArgLiteral, ArgPath}})
+	if got, err := tr.convertPathArgs([]string{"/x"}); err != nil || got[0] != "/x" {
+		t.Errorf("convertPathArgs with extra policies = %q, %v", got, err)
+	}
+}
+
+func TestArgTranslator_LongPaths(t *testing.T) {
+	t.Setenv("WSL_DISTRO_NAME", "Ubuntu")
+	long := "/mnt/c/" + strings.Repeat("dir/", 70) + "file.txt"
+	tr := newArgTranslator("tool.exe", CommandConfig{PathRules: PathRules{LongPaths: true}})
+	got, err := tr.translate(long)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := wslpath.LongPath(win(t, long)); got != want || !strings.HasPrefix(got, `\\?\`) {
+		t.Errorf("translate(long path) = %.40q..., want %.40q...", got, want)
+	}
+	if got, _ := tr.translate("/etc/hosts"); got != win(t, "/etc/hosts") {
+		t.Errorf("translate(/etc/hosts) = %q, want no prefix", got)
+	}
+}
+
+func TestArgTranslator_ResolveSymlinks(t *testing.T) {
+	t.Setenv("WSL_DISTRO_NAME", "Ubuntu")
+	target := t.TempDir()
+	link := filepath.Join(t.TempDir(), "proj")
+	if err := os.Symlink(target, link); err != nil {
+		t.Fatal(err)
+	}
+	tr := newArgTranslator("tool.exe", CommandConfig{PathRules: PathRules{ResolveSymlinks: true}})
+	got, err := tr.translate(link + "/out.txt")
+	if want := win(t, filepath.Join(target, "out.txt")); err != nil || got != want {
+		t.Errorf("translate(link) = %q, %v; want %q", got, err, want)
+	}
+}
@@ -0,0 +1,223 @@
+package bridge
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"os/exec"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"unicode/utf16"
+)
+
+// maxCommandLine is the longest command line CreateProcess accepts, in
+// UTF-16 code units, less room for the host path and fixed parameters.
+const maxCommandLine = 32767 - 512
+
+// powerShellPreamble makes PowerShell write UTF-8 and suppresses progress
+// records, which would otherwise be serialized to stderr as CLIXML.
+const powerShellPreamble = `$ProgressPreference = 'SilentlyContinue'
+[Console]::OutputEncoding = New-Object System.Text.UTF8Encoding $false
+$OutputEncoding = [Console]::OutputEncoding
+`
+
+// powerShellParamName matches the parameter names PowerShell accepts
+// without braces.
+var powerShellParamName = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// defaultPowerShellHost caches the host chosen when none is configured.
+var (
+	powerShellHostOnce sync.Once
+	powerShellHost     string
+)
+
+// PowerShellOptions configures PowerShell.
+type PowerShellOptions struct {
+	// Host is the PowerShell executable. If empty, pwsh.exe is used when it
+	// is on PATH and powershell.exe otherwise.
+	Host string
+
+	// Params are bound by name to the script's param() block. Values may
+	// be strings, booleans, integers, floats, nil, or slices of these.
+	// They are passed as PowerShell literals and never evaluated.
+	Params map[string]any
+
+	// LoadProfile loads the user's PowerShell profile. By default
+	// -NoProfile is passed.
+	LoadProfile bool
+
+	// ExecutionPolicy, if set, is passed as -ExecutionPolicy
+	// (e.g. "Bypass").
+	ExecutionPolicy string
+
+	// StopOnError sets $ErrorActionPreference to 'Stop', so that any error
+	// ends the script with a non-zero exit code.
+	StopOnError bool
+
+	// Config supplies the remaining settings, such as Timeout, Env and
+	// WorkDir. Its Command, Args, Encoding, Quoting and Interactive fields
+	// are ignored.
+	Config CommandConfig
+}
+
+// PowerShell runs script with PowerShell, passed as -EncodedCommand so no
+// quoting is involved, with -NoProfile -NonInteractive and UTF-8 output.
+// Errors are reported as for Execute.
+func PowerShell(ctx context.Context, script string, opts PowerShellOptions) (Output, error) {
+	config, err := powerShellConfig(script, opts)
+	if err != nil {
+		return Output{}, err
+	}
+	return Execute(ctx, config)
+}
+
+// powerShellConfig builds the CommandConfig that runs script.
+func powerShellConfig(script string, opts PowerShellOptions) (CommandConfig, error) {
+	config := opts.Config
+	config.Command = opts.Host
+	if config.Command == "" {
+		config.Command = defaultPowerShell()
+	}
+
+	wrapped, err := wrapPowerShellScript(script, opts)
+	if err != nil {
+		return CommandConfig{}, &StartError{Command: config.Command, Err: err}
+	}
+	encoded := encodePowerShell(wrapped)
+	if len(encoded) > maxCommandLine {
+		return CommandConfig{}, &StartError{
+			Command: config.Command,
+			Err:     fmt.Errorf("script too long for -EncodedCommand (%d characters encoded, limit %d)", len(encoded), maxCommandLine),
+		}
+	}
+
+	args := []string{"-NonInteractive", "-OutputFormat", "Text"}
+	if !opts.LoadProfile {
+		args = append([]string{"-NoProfile"}, args...)
+	}
+	if opts.ExecutionPolicy != "" {
+		args = append(args, "-ExecutionPolicy", opts.ExecutionPolicy)
+	}
+	config.Args = append(args, "-EncodedCommand", encoded)
+	config.Encoding = EncodingUTF8
+	config.Quoting = QuoteMSVCRT
+	config.Interactive = false
+	config.PTY = false
+	return config, nil
+}
+
+// defaultPowerShell returns pwsh.exe if it is on PATH, else powershell.exe.
+func defaultPowerShell() string {
+	powerShellHostOnce.Do(func() {
+		powerShellHost = "powershell.exe"
+		if _, err := exec.LookPath("pwsh.exe"); err == nil {
+			powerShellHost = "pwsh.exe"
+		}
+	})
+	return powerShellHost
+}
+
+// wrapPowerShellScript prepends the preamble and runs script as a script
+// block, so that its param() block, [CmdletBinding()] and #Requires stay
+// at the top of the block. opts.Params are bound to it by splatting a
+// hashtable of literals.
+func wrapPowerShellScript(script string, opts PowerShellOptions) (string, error) {
+	var b strings.Builder
+	b.WriteString(powerShellPreamble)
+	if opts.StopOnError {
+		b.WriteString("$ErrorActionPreference = 'Stop'\n")
+	}
+	if len(opts.Params) == 0 {
+		b.WriteString("& {\n")
+		b.WriteString(script)
+		b.WriteString("\n}\n")
+		return b.String(), nil
+	}
+
+	names := make([]string, 0, len(opts.Params))
+	for name := range opts.Params {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	b.WriteString("$__gowinbridgeParams = @{\n")
+	for _, name := range names {
+		if !powerShellParamName.MatchString(name) {
+			return "", fmt.Errorf("invalid PowerShell parameter name %q", name)
+		}
+		value, err := powerShellValue(reflect.ValueOf(opts.Params[name]))
+		if err != nil {
+			return "", fmt.Errorf("parameter %s: %w", name, err)
+		}
+		fmt.Fprintf(&b, "\t%s = %s\n", name, value)
+	}
+	b.WriteString("}\n& {\n")
+	b.WriteString(script)
+	b.WriteString("\n} @__gowinbridgeParams\n")
+	return b.String(), nil
+}
+
+// powerShellValue renders v as a PowerShell literal expression.
+func powerShellValue(v reflect.Value) (string, error) {
+	if !v.IsValid() {
+		return "$null", nil
+	}
+	switch v.Kind() {
+	case reflect.String:
+		return quotePowerShellLiteral(v.String()), nil
+	case reflect.Bool:
+		if v.Bool() {
+			return "$true", nil
+		}
+		return "$false", nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.FormatInt(v.Int(), 10), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return strconv.FormatUint(v.Uint(), 10), nil
+	case reflect.Float32, reflect.Float64:
+		f := v.Float()
+		switch {
+		case math.IsNaN(f):
+			return "[double]::NaN", nil
+		case math.IsInf(f, 1):
+			return "[double]::PositiveInfinity", nil
+		case math.IsInf(f, -1):
+			return "[double]::NegativeInfinity", nil
+		}
+		return "[double]" + strconv.FormatFloat(f, 'g', -1, 64), nil
+	case reflect.Interface, reflect.Pointer:
+		if v.IsNil() {
+			return "$null", nil
+		}
+		return powerShellValue(v.Elem())
+	case reflect.Slice, reflect.Array:
+		items := make([]string, v.Len())
+		for i := range items {
+			item, err := powerShellValue(v.Index(i))
+			if err != nil {
+				return "", err
+			}
+			items[i] = item
+		}
+		return "@(" + strings.Join(items, ", ") + ")", nil
+	}
+	return "", errors.New("unsupported value type " + v.Type().String())
+}
+
+// encodePowerShell encodes script for powershell.exe -EncodedCommand:
+// base64 of its UTF-16LE representation.
+func encodePowerShell(script string) string {
+	units := utf16.Encode([]rune(script))
+	buf := make([]byte, 2*len(units))
+	for i, u := range units {
+		binary.LittleEndian.PutUint16(buf[2*i:], u)
+	}
+	return base64.StdEncoding.EncodeToString(buf)
+}
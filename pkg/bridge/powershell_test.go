@@ -0,0 +1,167 @@
+package bridge
+
+import (
+	"encoding/base64"
+	"errors"
+	"math"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+	"unicode/utf16"
+)
+
+// decodePowerShell reverses encodePowerShell.
+func decodePowerShell(t *testing.T, encoded string) string {
+	t.Helper()
+	raw, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		t.Fatal(err)
+	}
+	units := make([]uint16, len(raw)/2)
+	for i := range units {
+		units[i] = uint16(raw[2*i]) | uint16(raw[2*i+1])<<8
+	}
+	return string(utf16.Decode(units))
+}
+
+func TestEncodePowerShell(t *testing.T) {
+	got, err := base64.StdEncoding.DecodeString(encodePowerShell("dir"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []byte{'d', 0, 'i', 0, 'r', 0}
+	if string(got) != string(want) {
+		t.Errorf("decoded = %v, want UTF-16LE %v", got, want)
+	}
+	if s := "Write-Output 'ü ✓ 😀'"; decodePowerShell(t, encodePowerShell(s)) != s {
+		t.Errorf("non-ASCII script did not round-trip")
+	}
+}
+
+func TestPowerShellConfig_Defaults(t *testing.T) {
+	config, err := powerShellConfig("Get-Date", PowerShellOptions{
+		Host:   "pwsh.exe",
+		Config: CommandConfig{Timeout: time.Minute, Encoding: EncodingCP1252, Interactive: true},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if config.Command != "pwsh.exe" || config.Timeout != time.Minute {
+		t.Errorf("config = %+v, want pwsh.exe with the given timeout", config)
+	}
+	if config.Encoding != EncodingUTF8 || config.Interactive {
+		t.Errorf("Encoding = %q, Interactive = %v; want utf8, false", config.Encoding, config.Interactive)
+	}
+
+	wantPrefix := []string{"-NoProfile", "-NonInteractive", "-OutputFormat", "Text", "-EncodedCommand"}
+	if len(config.Args) != len(wantPrefix)+1 || !reflect.DeepEqual(config.Args[:len(wantPrefix)], wantPrefix) {
+		t.Fatalf("Args = %q, want %q followed by the script", config.Args, wantPrefix)
+	}
+	script := decodePowerShell(t, config.Args[len(wantPrefix)])
+	if want := powerShellPreamble + "& {\nGet-Date\n}\n"; script != want {
+		t.Errorf("script = %q, want %q", script, want)
+	}
+}
+
+func TestPowerShellConfig_Options(t *testing.T) {
+	config, err := powerShellConfig("exit 0", PowerShellOptions{
+		Host:            "powershell.exe",
+		LoadProfile:     true,
+		ExecutionPolicy: "Bypass",
+		StopOnError:     true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"-NonInteractive", "-OutputFormat", "Text", "-ExecutionPolicy", "Bypass", "-EncodedCommand"}
+	if !reflect.DeepEqual(config.Args[:len(want)], want) {
+		t.Errorf("Args = %q, want prefix %q", config.Args, want)
+	}
+	if script := decodePowerShell(t, config.Args[len(want)]); !strings.Contains(script, "$ErrorActionPreference = 'Stop'") {
+		t.Errorf("script = %q, want ErrorActionPreference set", script)
+	}
+}
+
+func TestPowerShellConfig_Params(t *testing.T) {
+	config, err := powerShellConfig("param($Name, $Count) $Name", PowerShellOptions{
+		Host: "powershell.exe",
+		Params: map[string]any{
+			"Name":  "O'Brien; rm -r C:\\ $(evil)",
+			"Count": 3,
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	script := decodePowerShell(t, config.Args[len(config.Args)-1])
+	want := powerShellPreamble + "$__gowinbridgeParams = @{\n" +
+		"\tCount = 3\n" +
+		"\tName = 'O''Brien; rm -r C:\\ $(evil)'\n" +
+		"}\n& {\nparam($Name, $Count) $Name\n} @__gowinbridgeParams\n"
+	if script != want {
+		t.Errorf("script =\n%s\nwant\n%s", script, want)
+	}
+}
+
+func TestPowerShellConfig_ParamBlockWithoutParams(t *testing.T) {
+	// param() must open the script block, not follow the preamble.
+	body := "[CmdletBinding()]\nparam([string]$Name = 'world')\n\"hello $Name\""
+	config, err := powerShellConfig(body, PowerShellOptions{Host: "powershell.exe", StopOnError: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	script := decodePowerShell(t, config.Args[len(config.Args)-1])
+	want := powerShellPreamble + "$ErrorActionPreference = 'Stop'\n& {\n" + body + "\n}\n"
+	if script != want {
+		t.Errorf("script =\n%s\nwant\n%s", script, want)
+	}
+}
+
+func TestPowerShellConfig_Errors(t *testing.T) {
+	_, err := powerShellConfig("", PowerShellOptions{Host: "pwsh.exe", Params: map[string]any{"bad name": 1}})
+	var startErr *StartError
+	if !errors.As(err, &startErr) {
+		t.Errorf("invalid name: err = %v, want *StartError", err)
+	}
+
+	_, err = powerShellConfig("", PowerShellOptions{Host: "pwsh.exe", Params: map[string]any{"M": map[string]int{}}})
+	if !errors.As(err, &startErr) {
+		t.Errorf("unsupported value: err = %v, want *StartError", err)
+	}
+
+	_, err = powerShellConfig(strings.Repeat("x", maxCommandLine), PowerShellOptions{Host: "pwsh.exe"})
+	if !errors.As(err, &startErr) || !strings.Contains(err.Error(), "too long") {
+		t.Errorf("long script: err = %v, want too long *StartError", err)
+	}
+}
+
+func TestPowerShellValue(t *testing.T) {
+	s := "x"
+	tests := []struct {
+		in   any
+		want string
+	}{
+		{nil, "$null"},
+		{"", "''"},
+		{"it’s", "'it’’s'"},
+		{true, "$true"},
+		{false, "$false"},
+		{-42, "-42"},
+		{uint8(7), "7"},
+		{1.5, "[double]1.5"},
+		{-2e21, "[double]-2e+21"},
+		{math.NaN(), "[double]::NaN"},
+		{math.Inf(-1), "[double]::NegativeInfinity"},
+		{[]string{"a", "b c"}, "@('a', 'b c')"},
+		{[]any{1, "x", nil}, "@(1, 'x', $null)"},
+		{[]int{}, "@()"},
+		{&s, "'x'"},
+	}
+	for _, tt := range tests {
+		got, err := powerShellValue(reflect.ValueOf(tt.in))
+		if err != nil || got != tt.want {
+			t.Errorf("powerShellValue(%#v) = %q, %v; want %q", tt.in, got, err, tt.want)
+		}
+	}
+}
@@ -0,0 +1,214 @@
+//go:build linux
+
+package bridge
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"os/signal"
+	"strconv"
+	"syscall"
+	"time"
+
+	"golang.org/x/sys/unix"
+	"golang.org/x/term"
+)
+
+// ptyDrainTimeout bounds how long pty output is drained after the command exits.
+const ptyDrainTimeout = time.Second
+
+// openPTY allocates a pseudo-terminal pair via /dev/ptmx.
+func openPTY() (master, slave *os.File, err error) {
+	fd, err := unix.Open("/dev/ptmx", unix.O_RDWR|unix.O_NOCTTY|unix.O_CLOEXEC, 0)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to open /dev/ptmx: %w", err)
+	}
+	master = os.NewFile(uintptr(fd), "/dev/ptmx")
+
+	// Unlock the slave side and look up its number.
+	if err := unix.IoctlSetPointerInt(fd, unix.TIOCSPTLCK, 0); err != nil {
+		master.Close()
+		return nil, nil, fmt.Errorf("failed to unlock pty: %w", err)
+	}
+	n, err := unix.IoctlGetUint32(fd, unix.TIOCGPTN)
+	if err != nil {
+		master.Close()
+		return nil, nil, fmt.Errorf("failed to get pty number: %w", err)
+	}
+
+	slaveName := "/dev/pts/" + strconv.FormatUint(uint64(n), 10)
+	slave, err = os.OpenFile(slaveName, os.O_RDWR|unix.O_NOCTTY, 0)
+	if err != nil {
+		master.Close()
+		return nil, nil, fmt.Errorf("failed to open %s: %w", slaveName, err)
+	}
+	return master, slave, nil
+}
+
+// syncWinsize copies the window size of the terminal on fd to the pty.
+func syncWinsize(fd int, pty *os.File) error {
+	ws, err := unix.IoctlGetWinsize(fd, unix.TIOCGWINSZ)
+	if err != nil {
+		return err
+	}
+	return unix.IoctlSetWinsize(int(pty.Fd()), unix.TIOCSWINSZ, ws)
+}
+
+// copyInput copies in to dst until in ends or stop is called. If in is a
+// file, such as os.Stdin, it is polled together with a pipe that stop
+// closes, so a pending read is abandoned rather than left to take the
+// next keystroke. Other readers are copied with io.Copy, which stop
+// cannot interrupt.
+func copyInput(dst io.Writer, in io.Reader) (stop func()) {
+	f, ok := in.(*os.File)
+	if !ok {
+		go io.Copy(dst, in)
+		return func() {}
+	}
+	pr, pw, err := os.Pipe()
+	if err != nil {
+		go io.Copy(dst, in)
+		return func() {}
+	}
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		defer pr.Close()
+		fd := int(f.Fd())
+		fds := []unix.PollFd{
+			{Fd: int32(fd), Events: unix.POLLIN},
+			{Fd: int32(pr.Fd()), Events: unix.POLLIN},
+		}
+		buf := make([]byte, 32*1024)
+		for {
+			if _, err := unix.Poll(fds, -1); err != nil {
+				if err == unix.EINTR {
+					continue
+				}
+				return
+			}
+			if fds[1].Revents != 0 || fds[0].Revents&unix.POLLNVAL != 0 {
+				return
+			}
+			if fds[0].Revents == 0 {
+				continue
+			}
+			n, err := unix.Read(fd, buf)
+			if n > 0 {
+				if _, err := dst.Write(buf[:n]); err != nil {
+					return
+				}
+			}
+			if err == unix.EINTR || err == unix.EAGAIN {
+				continue
+			}
+			if err != nil || n == 0 {
+				return
+			}
+		}
+	}()
+	return func() {
+		pw.Close()
+		<-done
+	}
+}
+
+// executePTY runs the command attached to a freshly allocated pseudo-terminal.
+// When the local stdin is a terminal it is switched to raw mode for the
+// duration of the command, and window size changes are forwarded.
+func executePTY(cmd *command, config CommandConfig, in io.Reader, out io.Writer) (Output, error) {
+	master, slave, err := openPTY()
+	if err != nil {
+		return Output{}, &StartError{Command: config.Command, Err: err}
+	}
+	defer master.Close()
+
+	// The child gets the slave as its controlling terminal on all three fds.
+	cmd.Stdin, cmd.Stdout, cmd.Stderr = slave, slave, slave
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setsid: true, Setctty: true}
+
+	stdinFd := int(os.Stdin.Fd())
+	localTTY := in == os.Stdin && term.IsTerminal(stdinFd)
+
+	if localTTY {
+		if err := syncWinsize(stdinFd, master); err != nil {
+			slave.Close()
+			return Output{}, &StartError{Command: config.Command, Err: fmt.Errorf("failed to set pty size: %w", err)}
+		}
+	}
+
+	start := time.Now()
+
+	if err := cmd.Start(); err != nil {
+		slave.Close()
+		return Output{}, &StartError{Command: config.Command, Err: err}
+	}
+	// Only the child needs the slave; keeping it open would prevent EOF.
+	slave.Close()
+
+	if localTTY {
+		state, err := term.MakeRaw(stdinFd)
+		if err != nil {
+			cmd.kill()
+			cmd.Wait()
+			return Output{}, fmt.Errorf("failed to put terminal in raw mode: %w", err)
+		}
+		restore := setTerminalRestore(func() { term.Restore(stdinFd, state) })
+		defer restore()
+
+		winch := make(chan os.Signal, 1)
+		signal.Notify(winch, syscall.SIGWINCH)
+		defer func() {
+			signal.Stop(winch)
+			close(winch)
+		}()
+		go func() {
+			for range winch {
+				syncWinsize(stdinFd, master)
+			}
+		}()
+	}
+
+	// Stdin is copied until the command exits, so that no input meant
+	// for whatever runs next is consumed.
+	stopInput := copyInput(master, in)
+
+	copyDone := make(chan error, 1)
+	go func() {
+		_, err := io.Copy(out, master)
+		copyDone <- err
+	}()
+
+	waitErr := cmd.Wait()
+	duration := time.Since(start)
+	stopInput()
+
+	// Reading the master fails with EIO once the child side is gone. A
+	// grandchild may still hold the slave open; don't wait on it forever.
+	var copyErr error
+	select {
+	case copyErr = <-copyDone:
+	case <-time.After(ptyDrainTimeout):
+		master.Close()
+		<-copyDone
+	}
+	if copyErr != nil && !errors.Is(copyErr, syscall.EIO) {
+		return Output{Duration: duration}, fmt.Errorf("failed to copy pty output: %w", copyErr)
+	}
+
+	output := Output{Duration: duration}
+
+	if waitErr != nil {
+		if exitErr, ok := waitErr.(*exec.ExitError); ok {
+			output.ExitCode = exitErr.ExitCode()
+		} else {
+			return output, fmt.Errorf("command execution failed: %w", waitErr)
+		}
+	}
+
+	return output, nil
+}
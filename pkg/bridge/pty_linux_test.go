@@ -0,0 +1,108 @@
+//go:build linux
+
+package bridge
+
+import (
+	"bytes"
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func requirePTY(t *testing.T) {
+	t.Helper()
+	master, slave, err := openPTY()
+	if err != nil {
+		t.Skipf("pty not available: %v", err)
+	}
+	master.Close()
+	slave.Close()
+	if _, err := exec.LookPath("sh"); err != nil {
+		t.Skip("sh not available")
+	}
+}
+
+func TestExecutePTY_AttachesTerminal(t *testing.T) {
+	requirePTY(t)
+
+	script := `test -t 0 && test -t 1 && echo is-a-tty; read line; echo "got:$line"`
+	var out bytes.Buffer
+	got, err := executePTY(&command{Cmd: exec.Command("sh", "-c", script)}, CommandConfig{Command: "sh"},
+		strings.NewReader("hello\n"), &out)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got.ExitCode != 0 {
+		t.Errorf("ExitCode = %d, want 0", got.ExitCode)
+	}
+	if !strings.Contains(out.String(), "is-a-tty") {
+		t.Errorf("child did not see a terminal; output %q", out.String())
+	}
+	if !strings.Contains(out.String(), "got:hello") {
+		t.Errorf("input not forwarded through pty; output %q", out.String())
+	}
+}
+
+func TestExecutePTY_ExitCode(t *testing.T) {
+	requirePTY(t)
+
+	var out bytes.Buffer
+	got, err := executePTY(&command{Cmd: exec.Command("sh", "-c", "exit 3")}, CommandConfig{Command: "sh"},
+		strings.NewReader(""), &out)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got.ExitCode != 3 {
+		t.Errorf("ExitCode = %d, want 3", got.ExitCode)
+	}
+}
+
+func TestCopyInput_StopLeavesLaterInput(t *testing.T) {
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	defer w.Close()
+
+	var dst syncBuffer
+	stop := copyInput(&dst, r)
+	w.Write([]byte("typed during the command"))
+	deadline := time.Now().Add(2 * time.Second)
+	for dst.String() != "typed during the command" && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+	stop()
+	if got := dst.String(); got != "typed during the command" {
+		t.Fatalf("copied %q", got)
+	}
+
+	// Input after the command has exited belongs to the next reader.
+	w.Write([]byte("next"))
+	buf := make([]byte, 16)
+	n, _ := r.Read(buf)
+	if string(buf[:n]) != "next" {
+		t.Errorf("next reader got %q, want %q", buf[:n], "next")
+	}
+}
+
+// syncBuffer is a bytes.Buffer safe for one writer and one reader.
+type syncBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *syncBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
@@ -0,0 +1,13 @@
+//go:build !linux
+
+package bridge
+
+import (
+	"errors"
+	"io"
+)
+
+// executePTY is only supported on Linux.
+func executePTY(cmd *command, config CommandConfig, in io.Reader, out io.Writer) (Output, error) {
+	return Output{}, &StartError{Command: config.Command, Err: errors.New("pty mode is only supported on linux")}
+}
@@ -0,0 +1,366 @@
+package bridge
+
+import (
+	"fmt"
+	"strings"
+)
+
+// QuoteRule selects how arguments are quoted into the Windows command line.
+//
+// Windows processes receive a single command-line string, not an argv
+// array, and each program splits it itself. WSL interop builds that string
+// by joining the arguments with spaces and wrapping those that contain
+// whitespace in double quotes, without escaping anything else. Arguments
+// containing quotes, trailing backslashes or cmd.exe metacharacters are
+// therefore mangled unless they are quoted for the target first.
+type QuoteRule int
+
+// QuoteRule values.
+const (
+	// QuoteAuto picks a rule from the target. Arguments of batch files
+	// are always escaped as with QuoteCmd. For cmd.exe, the command string
+	// after /c or /k is quoted as QuoteMSVCRT, so that the operators in
+	// /c "dir && echo x" work, and the arguments after it are escaped as
+	// with QuoteCmd except that % and ! are left for cmd.exe to expand.
+	// Everything else, PowerShell included, is quoted as QuoteMSVCRT, so
+	// the words after -Command are still evaluated.
+	QuoteAuto QuoteRule = iota
+	// QuoteNone passes arguments to WSL interop unchanged.
+	QuoteNone
+	// QuoteMSVCRT quotes for CommandLineToArgvW and the MSVCRT startup
+	// code, which most Windows programs use to build argv.
+	QuoteMSVCRT
+	// QuoteCmd quotes as QuoteMSVCRT and then escapes every cmd.exe
+	// metacharacter with ^, so that cmd.exe /c passes arguments through
+	// literally: &, |, <, >, (, ), ^, %, ! and quotes lose their meaning.
+	// Tab characters cannot be passed this way.
+	QuoteCmd
+	// QuotePowerShell quotes as QuoteMSVCRT. In addition, arguments that
+	// follow the script text of -Command are turned into single-quoted
+	// PowerShell strings if they contain characters PowerShell would
+	// interpret, so they arrive as literal values.
+	QuotePowerShell
+)
+
+// String returns the name accepted by ParseQuoteRule.
+func (r QuoteRule) String() string {
+	switch r {
+	case QuoteNone:
+		return "none"
+	case QuoteMSVCRT:
+		return "msvcrt"
+	case QuoteCmd:
+		return "cmd"
+	case QuotePowerShell:
+		return "powershell"
+	default:
+		return "auto"
+	}
+}
+
+// ParseQuoteRule parses a rule name: "auto" (or ""), "none", "msvcrt",
+// "cmd" or "powershell".
+func ParseQuoteRule(s string) (QuoteRule, error) {
+	switch strings.ToLower(s) {
+	case "", "auto":
+		return QuoteAuto, nil
+	case "none":
+		return QuoteNone, nil
+	case "msvcrt":
+		return QuoteMSVCRT, nil
+	case "cmd":
+		return QuoteCmd, nil
+	case "powershell", "pwsh":
+		return QuotePowerShell, nil
+	default:
+		return QuoteAuto, fmt.Errorf("unknown quoting rule %q", s)
+	}
+}
+
+// quoteCmdShell is the rule QuoteAuto stands for with cmd.exe; see
+// quoteCmdShellArgs.
+const quoteCmdShell QuoteRule = -1
+
+// quoteRuleFor returns the rule QuoteAuto selects for command. Batch files
+// are not covered: batchArgs builds their cmd.exe line.
+func quoteRuleFor(command string) QuoteRule {
+	base := strings.ToLower(command[strings.LastIndexAny(command, `/\`)+1:])
+	if base == "cmd" || base == "cmd.exe" {
+		return quoteCmdShell
+	}
+	return QuoteMSVCRT
+}
+
+// QuoteArg quotes a single argument for a Windows command line under rule.
+// QuoteAuto is treated as QuoteMSVCRT, and QuotePowerShell always produces
+// a single-quoted PowerShell string.
+func QuoteArg(arg string, rule QuoteRule) string {
+	switch rule {
+	case QuoteNone:
+		return arg
+	case QuoteCmd:
+		return quoteCmd(arg)
+	case QuotePowerShell:
+		return quoteMSVCRT(quotePowerShellLiteral(arg))
+	default:
+		return quoteMSVCRT(arg)
+	}
+}
+
+// JoinCommandLine quotes args under rule and joins them into the tail of a
+// Windows command line, everything after the program name. QuoteAuto is
+// treated as QuoteMSVCRT.
+func JoinCommandLine(args []string, rule QuoteRule) string {
+	return strings.Join(quoteArgs(args, rule), " ")
+}
+
+// quoteArgs quotes each of args under rule.
+func quoteArgs(args []string, rule QuoteRule) []string {
+	switch rule {
+	case QuotePowerShell:
+		return quotePowerShellArgs(args)
+	case quoteCmdShell:
+		return quoteCmdShellArgs(args)
+	}
+	quoted := make([]string, len(args))
+	for i, arg := range args {
+		quoted[i] = QuoteArg(arg, rule)
+	}
+	return quoted
+}
+
+// SplitCommandLine splits the tail of a Windows command line into
+// arguments the way CommandLineToArgvW and the MSVCRT startup code do.
+// It is the inverse of JoinCommandLine with QuoteMSVCRT.
+func SplitCommandLine(line string) []string {
+	var (
+		args    []string
+		cur     strings.Builder
+		inArg   bool
+		quoted  bool
+		slashes int
+	)
+	for i := 0; i < len(line); i++ {
+		c := line[i]
+		switch {
+		case c == '\\':
+			slashes++
+			inArg = true
+			continue
+		case c == '"':
+			cur.WriteString(strings.Repeat(`\`, slashes/2))
+			inArg = true
+			if slashes%2 == 1 {
+				cur.WriteByte('"')
+			} else if quoted && i+1 < len(line) && line[i+1] == '"' {
+				// "" inside quotes is a literal quote.
+				cur.WriteByte('"')
+				i++
+			} else {
+				quoted = !quoted
+			}
+		case (c == ' ' || c == '\t') && !quoted:
+			cur.WriteString(strings.Repeat(`\`, slashes))
+			if inArg {
+				args = append(args, cur.String())
+				cur.Reset()
+				inArg = false
+			}
+		default:
+			cur.WriteString(strings.Repeat(`\`, slashes))
+			cur.WriteByte(c)
+			inArg = true
+		}
+		slashes = 0
+	}
+	cur.WriteString(strings.Repeat(`\`, slashes))
+	if inArg {
+		args = append(args, cur.String())
+	}
+	return args
+}
+
+// quoteMSVCRT quotes arg so that CommandLineToArgvW parses it back
+// unchanged. Arguments without whitespace or quotes are left as they are.
+func quoteMSVCRT(arg string) string {
+	if arg != "" && !strings.ContainsAny(arg, " \t\n\v\"") {
+		return arg
+	}
+	var b strings.Builder
+	b.WriteByte('"')
+	slashes := 0
+	for i := 0; i < len(arg); i++ {
+		c := arg[i]
+		if c == '\\' {
+			slashes++
+			continue
+		}
+		if c == '"' {
+			// Backslashes before a quote are escapes; double them and
+			// escape the quote itself.
+			b.WriteString(strings.Repeat(`\`, 2*slashes+1))
+		} else {
+			b.WriteString(strings.Repeat(`\`, slashes))
+		}
+		b.WriteByte(c)
+		slashes = 0
+	}
+	// Trailing backslashes precede the closing quote.
+	b.WriteString(strings.Repeat(`\`, 2*slashes))
+	b.WriteByte('"')
+	return b.String()
+}
+
+// cmdMetaChars are the characters quoteCmd escapes with ^. Quotes are
+// escaped too, so cmd.exe never enters its quoted state, and so is
+// whitespace, so that runs of spaces survive WSL interop.
+const cmdMetaChars = "()%!^\"<>&| \t"
+
+// cmdShellChars are the characters quoteCmdShellArgs escapes: those of
+// cmdMetaChars but % and !.
+const cmdShellChars = "()^\"<>&| \t"
+
+// quoteCmd quotes arg for a program started through cmd.exe.
+func quoteCmd(arg string) string {
+	return escapeCmd(quoteMSVCRT(arg), cmdMetaChars)
+}
+
+// escapeCmd prefixes each of chars in q with ^.
+func escapeCmd(q, chars string) string {
+	var b strings.Builder
+	for i := 0; i < len(q); i++ {
+		if strings.IndexByte(chars, q[i]) >= 0 {
+			b.WriteByte('^')
+		}
+		b.WriteByte(q[i])
+	}
+	return b.String()
+}
+
+// quoteCmdShellArgs quotes the arguments of cmd.exe for QuoteAuto. The
+// command string after /c or /k is the caller's cmd.exe syntax and passes
+// as is; the arguments after it are the command's data, so operators in
+// them are escaped, while variables still expand.
+func quoteCmdShellArgs(args []string) []string {
+	quoted := make([]string, len(args))
+	state := 0 // 0: cmd.exe switches, 1: command string next, 2: its arguments
+	for i, arg := range args {
+		if state == 2 {
+			quoted[i] = escapeCmd(quoteMSVCRT(arg), cmdShellChars)
+		} else {
+			quoted[i] = quoteMSVCRT(arg)
+		}
+		switch {
+		case state == 0 && (strings.EqualFold(arg, "/c") || strings.EqualFold(arg, "/k")):
+			state = 1
+		case state == 1:
+			state = 2
+		}
+	}
+	return quoted
+}
+
+// powerShellQuotes are the characters PowerShell accepts as single quotes.
+const powerShellQuotes = "'‘’‚‛"
+
+// powerShellSpecial are the characters that make PowerShell interpret a
+// bare word as something other than a literal string.
+const powerShellSpecial = " \t\n\"`$;&|(){}@,<>#" + powerShellQuotes + "“”„"
+
+// quotePowerShellLiteral renders arg as a single-quoted PowerShell string.
+func quotePowerShellLiteral(arg string) string {
+	var b strings.Builder
+	b.WriteByte('\'')
+	for _, r := range arg {
+		if strings.ContainsRune(powerShellQuotes, r) {
+			b.WriteRune(r)
+		}
+		b.WriteRune(r)
+	}
+	b.WriteByte('\'')
+	return b.String()
+}
+
+// quotePowerShellArgs quotes the arguments of powershell.exe or pwsh.exe.
+// The script text after -Command is passed as is; PowerShell joins it with
+// the arguments that follow, so those are made literal where needed.
+func quotePowerShellArgs(args []string) []string {
+	quoted := make([]string, len(args))
+	state := 0 // 0: host parameters, 1: script text next, 2: script arguments
+	for i, arg := range args {
+		if state == 2 && (arg == "" || strings.ContainsAny(arg, powerShellSpecial)) {
+			quoted[i] = quoteMSVCRT(quotePowerShellLiteral(arg))
+		} else {
+			quoted[i] = quoteMSVCRT(arg)
+		}
+		switch {
+		case state == 0 && isPowerShellCommandFlag(arg):
+			state = 1
+		case state == 1:
+			state = 2
+		}
+	}
+	return quoted
+}
+
+// isPowerShellCommandFlag reports whether arg is the -Command parameter.
+func isPowerShellCommandFlag(arg string) bool {
+	if len(arg) < 2 || (arg[0] != '-' && arg[0] != '/') {
+		return false
+	}
+	switch strings.ToLower(arg[1:]) {
+	case "c", "command":
+		return true
+	}
+	return false
+}
+
+// interopArgs quotes args for the Windows program under rule and returns
+// the argv to hand to WSL interop, such that interop's own joining yields
+// exactly the quoted command line.
+func interopArgs(args []string, rule QuoteRule) []string {
+	if rule == QuoteNone {
+		return args
+	}
+	var out []string
+	for _, q := range quoteArgs(args, rule) {
+		out = append(out, interopSplit(q)...)
+	}
+	return out
+}
+
+// batchArgs returns the interop arguments that run a batch file through
+// cmd.exe. hostArgs end with the script path and args are the script's
+// arguments; together they become one quoted string after /c:
+//
+//	/d /s /c ""C:\Program Files\build.cmd" release ^"a^ b^" x^&y"
+//
+// With /s, cmd.exe strips exactly that outer pair of quotes, so the quotes
+// around the script path survive. The arguments are escaped with QuoteCmd
+// whatever the configured rule, since the caller never wrote this line and
+// a metacharacter in them must not run a second command.
+func batchArgs(hostArgs, args []string) []string {
+	n := len(hostArgs) - 1
+	line := `"` + hostArgs[n] + `"`
+	if len(args) > 0 {
+		line += " " + JoinCommandLine(args, QuoteCmd)
+	}
+	return append(hostArgs[:n:n], interopSplit(`"`+line+`"`)...)
+}
+
+// interopSplit returns the arguments from which WSL interop reproduces the
+// command-line fragment q. Interop wraps arguments that are empty or
+// contain whitespace in quotes, so a quoted fragment loses its quotes and
+// any other fragment is split at its (escaped) spaces.
+func interopSplit(q string) []string {
+	if q != "" && !strings.ContainsAny(q, " \t") {
+		return []string{q}
+	}
+	if len(q) >= 2 && q[0] == '"' && q[len(q)-1] == '"' {
+		inner := q[1 : len(q)-1]
+		if inner == "" || strings.ContainsAny(inner, " \t") {
+			return []string{inner}
+		}
+	}
+	return strings.Split(q, " ")
+}
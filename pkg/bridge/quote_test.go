@@ -0,0 +1,363 @@
+package bridge
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// interopJoin builds the Windows command line the way WSL interop does:
+// arguments joined with spaces, quoted if empty or containing whitespace.
+func interopJoin(args []string) string {
+	parts := make([]string, len(args))
+	for i, arg := range args {
+		if arg == "" || strings.ContainsAny(arg, " \t") {
+			arg = `"` + arg + `"`
+		}
+		parts[i] = arg
+	}
+	return strings.Join(parts, " ")
+}
+
+// cmdUnescape applies cmd.exe's caret processing to line and fails on any
+// metacharacter cmd.exe would interpret.
+func cmdUnescape(line string) (string, error) {
+	var b strings.Builder
+	quoted := false
+	for i := 0; i < len(line); i++ {
+		c := line[i]
+		switch {
+		case c == '"':
+			quoted = !quoted
+		case quoted:
+			if c == '%' || c == '!' {
+				return "", fmt.Errorf("%c inside quotes at %d", c, i)
+			}
+		case c == '^':
+			i++
+			if i == len(line) {
+				return "", fmt.Errorf("dangling ^")
+			}
+			c = line[i]
+		case strings.IndexByte("&|<>()%!", c) >= 0:
+			return "", fmt.Errorf("unescaped %c at %d", c, i)
+		}
+		b.WriteByte(c)
+	}
+	return b.String(), nil
+}
+
+// powerShellUnquote reverses quotePowerShellLiteral.
+func powerShellUnquote(s string) string {
+	rs := []rune(s)
+	if len(rs) < 2 || rs[0] != '\'' || rs[len(rs)-1] != '\'' {
+		return s
+	}
+	var b strings.Builder
+	rs = rs[1 : len(rs)-1]
+	for i := 0; i < len(rs); i++ {
+		b.WriteRune(rs[i])
+		if strings.ContainsRune(powerShellQuotes, rs[i]) {
+			i++
+		}
+	}
+	return b.String()
+}
+
+var quoteCases = [][]string{
+	{},
+	{"hello"},
+	{""},
+	{"", ""},
+	{"a b"},
+	{"a  b", " lead", "trail "},
+	{`C:\Program Files\`},
+	{`C:\dir\\`},
+	{`\\server\share`},
+	{`say "hi"`},
+	{`"`, `""`, `\"`, `\\"`},
+	{`a\"b`, `a\\"b`},
+	{`x & y`, `a|b`, `<in>`, `(1)`},
+	{`100%`, `%PATH%`, `!x!`, `^`},
+	{`echo "a & b" ^ %c%`},
+	{"/c", "dir", `C:\Users\me\My Documents`},
+	{"it's", "‘smart’"},
+	{"line\nbreak", "v\vtab"},
+	{"unicode ✓ ü", "日本"},
+}
+
+func TestQuoteMSVCRT_RoundTrip(t *testing.T) {
+	for _, args := range quoteCases {
+		line := JoinCommandLine(args, QuoteMSVCRT)
+		if got := SplitCommandLine(line); !equalArgs(got, args) {
+			t.Errorf("JoinCommandLine(%q) = %s, parsed back as %q", args, line, got)
+		}
+		viaInterop := interopJoin(interopArgs(args, QuoteMSVCRT))
+		if viaInterop != line {
+			t.Errorf("interop line for %q = %s, want %s", args, viaInterop, line)
+		}
+	}
+}
+
+func TestQuoteCmd_RoundTrip(t *testing.T) {
+	for _, args := range quoteCases {
+		if strings.ContainsRune(strings.Join(args, ""), '\t') {
+			continue
+		}
+		line := interopJoin(interopArgs(args, QuoteCmd))
+		if line != JoinCommandLine(args, QuoteCmd) {
+			t.Errorf("interop line for %q = %s, want %s", args, line, JoinCommandLine(args, QuoteCmd))
+		}
+		unescaped, err := cmdUnescape(line)
+		if err != nil {
+			t.Errorf("cmd.exe would interpret %s: %v", line, err)
+			continue
+		}
+		if got := SplitCommandLine(unescaped); !equalArgs(got, args) {
+			t.Errorf("args %q via cmd.exe = %q (line %s)", args, got, line)
+		}
+	}
+}
+
+func TestQuotePowerShell(t *testing.T) {
+	tests := []struct {
+		args []string
+		want []string // as seen by PowerShell after CommandLineToArgvW
+	}{
+		{
+			[]string{"-NoProfile", "-Command", "Write-Output $env:X", "a b", "plain", "it's"},
+			[]string{"-NoProfile", "-Command", "Write-Output $env:X", "'a b'", "plain", "'it''s'"},
+		},
+		{
+			[]string{"-c", "Get-Item", "-Path", `C:\x y`},
+			[]string{"-c", "Get-Item", "-Path", `'C:\x y'`},
+		},
+		{
+			[]string{"-File", "script.ps1", "a b", "$x"},
+			[]string{"-File", "script.ps1", "a b", "$x"},
+		},
+		{
+			[]string{"-Command", "echo", ""},
+			[]string{"-Command", "echo", "''"},
+		},
+	}
+	for _, tt := range tests {
+		line := interopJoin(interopArgs(tt.args, QuotePowerShell))
+		got := SplitCommandLine(line)
+		if !equalArgs(got, tt.want) {
+			t.Errorf("args %q parsed as %q, want %q (line %s)", tt.args, got, tt.want, line)
+		}
+		for i, arg := range got {
+			if unquoted := powerShellUnquote(arg); unquoted != tt.args[i] {
+				t.Errorf("arg %d: PowerShell sees %q, want %q", i, unquoted, tt.args[i])
+			}
+		}
+	}
+}
+
+func TestQuote_Exhaustive(t *testing.T) {
+	// Every string of up to three characters from an alphabet of the
+	// characters that matter, alone and paired with another argument.
+	alphabet := []string{"a", " ", `"`, `\`, "&", "%", "^", "'"}
+	words := []string{""}
+	for n, prev := 0, []string{""}; n < 3; n++ {
+		var next []string
+		for _, w := range prev {
+			for _, c := range alphabet {
+				next = append(next, w+c)
+			}
+		}
+		words = append(words, next...)
+		prev = next
+	}
+
+	for _, w := range words {
+		for _, args := range [][]string{{w}, {w, `x\`}, {`"`, w}} {
+			msvcrt := interopJoin(interopArgs(args, QuoteMSVCRT))
+			if got := SplitCommandLine(msvcrt); !equalArgs(got, args) {
+				t.Fatalf("msvcrt: %q -> %s -> %q", args, msvcrt, got)
+			}
+
+			cmd := interopJoin(interopArgs(args, QuoteCmd))
+			unescaped, err := cmdUnescape(cmd)
+			if err != nil {
+				t.Fatalf("cmd: %q -> %s: %v", args, cmd, err)
+			}
+			if got := SplitCommandLine(unescaped); !equalArgs(got, args) {
+				t.Fatalf("cmd: %q -> %s -> %q", args, cmd, got)
+			}
+
+			ps := interopJoin(interopArgs(append([]string{"-Command", "echo"}, args...), QuotePowerShell))
+			got := SplitCommandLine(ps)
+			if len(got) != len(args)+2 {
+				t.Fatalf("powershell: %q -> %s -> %q", args, ps, got)
+			}
+			for i, arg := range args {
+				if unquoted := powerShellUnquote(got[i+2]); unquoted != arg {
+					t.Fatalf("powershell: %q -> %s -> %q", args, ps, got)
+				}
+			}
+		}
+	}
+}
+
+func TestSplitCommandLine(t *testing.T) {
+	tests := []struct {
+		line string
+		want []string
+	}{
+		{``, nil},
+		{`  a   b  `, []string{"a", "b"}},
+		{`"a b" c`, []string{"a b", "c"}},
+		{`a\\b`, []string{`a\\b`}},
+		{`a\\\"b`, []string{`a\"b`}},
+		{`a\\\\"b c"`, []string{`a\\b c`}},
+		{`"a""b"`, []string{`a"b`}},
+		{`"" x`, []string{"", "x"}},
+		{`ab"c d"e`, []string{"abc de"}},
+		{"a\tb", []string{"a", "b"}},
+		{`trailing\`, []string{`trailing\`}},
+	}
+	for _, tt := range tests {
+		if got := SplitCommandLine(tt.line); !equalArgs(got, tt.want) {
+			t.Errorf("SplitCommandLine(%s) = %q, want %q", tt.line, got, tt.want)
+		}
+	}
+}
+
+func TestQuoteArg(t *testing.T) {
+	tests := []struct {
+		arg  string
+		rule QuoteRule
+		want string
+	}{
+		{"plain", QuoteMSVCRT, "plain"},
+		{"", QuoteMSVCRT, `""`},
+		{"a b", QuoteMSVCRT, `"a b"`},
+		{`C:\a b\`, QuoteMSVCRT, `"C:\a b\\"`},
+		{`say "hi"`, QuoteMSVCRT, `"say \"hi\""`},
+		{`C:\no\space\`, QuoteMSVCRT, `C:\no\space\`},
+		{"a&b", QuoteCmd, "a^&b"},
+		{"%PATH%", QuoteCmd, "^%PATH^%"},
+		{"a b", QuoteCmd, `^"a^ b^"`},
+		{"it's", QuotePowerShell, `'it''s'`},
+		{`a "b"`, QuoteNone, `a "b"`},
+		{"a b", QuoteAuto, `"a b"`},
+	}
+	for _, tt := range tests {
+		if got := QuoteArg(tt.arg, tt.rule); got != tt.want {
+			t.Errorf("QuoteArg(%q, %s) = %s, want %s", tt.arg, tt.rule, got, tt.want)
+		}
+	}
+}
+
+func TestBatchArgs(t *testing.T) {
+	host := []string{"/d", "/s", "/c", `C:\Program Files\tools\build.cmd`}
+	tests := []struct {
+		args []string
+		want string
+	}{
+		{[]string{"release", "a b", "x&calc"},
+			`/d /s /c ""C:\Program Files\tools\build.cmd" release ^"a^ b^" x^&calc"`},
+		{nil, `/d /s /c ""C:\Program Files\tools\build.cmd""`},
+	}
+	for _, tt := range tests {
+		got := interopJoin(batchArgs(host, tt.args))
+		if got != tt.want {
+			t.Errorf("batchArgs(%q) = %s, want %s", tt.args, got, tt.want)
+			continue
+		}
+		if len(tt.args) == 0 {
+			continue
+		}
+		// cmd.exe /s strips the outer quotes; after the quoted script path,
+		// caret processing must leave exactly the arguments.
+		rest := strings.TrimSuffix(strings.TrimPrefix(got, `/d /s /c ""C:\Program Files\tools\build.cmd"`), `"`)
+		unescaped, err := cmdUnescape(rest)
+		if err != nil {
+			t.Errorf("batchArgs(%q): %v", tt.args, err)
+		} else if split := SplitCommandLine(unescaped); !equalArgs(split, tt.args) {
+			t.Errorf("batchArgs(%q): script receives %q", tt.args, split)
+		}
+	}
+
+	if got := interopJoin(batchArgs([]string{"/d", "/s", "/c", `C:\ci\setup.bat`}, nil)); got != `/d /s /c ""C:\ci\setup.bat""` {
+		t.Errorf("batchArgs without spaces = %s", got)
+	}
+}
+
+func TestQuoteAuto_Cmd(t *testing.T) {
+	// The command string after /c keeps its operators, the arguments after
+	// it lose theirs, and variables expand in both.
+	tests := []struct {
+		args []string
+		want string
+	}{
+		{[]string{"/c", "echo", "%MY_VAR%"}, `/c echo %MY_VAR%`},
+		{[]string{"/c", "echo", "!MY_VAR!"}, `/c echo !MY_VAR!`},
+		{[]string{"/c", "dir && echo x"}, `/c "dir && echo x"`},
+		{[]string{"/d", "/c", "echo", "R&D", "a|b"}, `/d /c echo R^&D a^|b`},
+		{[]string{"/c", "echo", "a b"}, `/c echo ^"a^ b^"`},
+		{[]string{"/K", "type", "x>y"}, `/K type x^>y`},
+	}
+	for _, tt := range tests {
+		if got := interopJoin(interopArgs(tt.args, quoteRuleFor("cmd.exe"))); got != tt.want {
+			t.Errorf("auto quoting of cmd.exe %q = %s, want %s", tt.args, got, tt.want)
+		}
+	}
+
+	// PowerShell still evaluates the words after -Command.
+	args := []string{"-Command", "Write-Output", "$env:PATH"}
+	if got := interopJoin(interopArgs(args, quoteRuleFor("powershell.exe"))); got != `-Command Write-Output $env:PATH` {
+		t.Errorf("auto quoting of powershell.exe %q = %s", args, got)
+	}
+}
+
+func TestQuoteRuleFor(t *testing.T) {
+	tests := map[string]QuoteRule{
+		"cmd.exe":                          quoteCmdShell,
+		"CMD":                              quoteCmdShell,
+		"/mnt/c/Windows/System32/cmd.exe":  quoteCmdShell,
+		`C:\Windows\System32\cmd.exe`:      quoteCmdShell,
+		"powershell.exe":                   QuoteMSVCRT,
+		"git.exe":                          QuoteMSVCRT,
+		"/mnt/c/Program Files/cmdtool.exe": QuoteMSVCRT,
+	}
+	for command, want := range tests {
+		if got := quoteRuleFor(command); got != want {
+			t.Errorf("quoteRuleFor(%q) = %d, want %d", command, got, want)
+		}
+	}
+}
+
+func TestParseQuoteRule(t *testing.T) {
+	for _, rule := range []QuoteRule{QuoteAuto, QuoteNone, QuoteMSVCRT, QuoteCmd, QuotePowerShell} {
+		got, err := ParseQuoteRule(rule.String())
+		if err != nil || got != rule {
+			t.Errorf("ParseQuoteRule(%q) = %s, %v", rule.String(), got, err)
+		}
+	}
+	if _, err := ParseQuoteRule("bash"); err == nil {
+		t.Error("ParseQuoteRule(bash) succeeded, want error")
+	}
+}
+
+func TestInteropArgs_None(t *testing.T) {
+	args := []string{`a "b"`, "c d"}
+	if got := interopArgs(args, QuoteNone); !reflect.DeepEqual(got, args) {
+		t.Errorf("interopArgs(QuoteNone) = %q, want %q", got, args)
+	}
+}
+
+func equalArgs(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
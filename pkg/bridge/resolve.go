@@ -0,0 +1,288 @@
+package bridge
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/sibikrish3000/gowinbridge/pkg/wslpath"
+)
+
+// defaultPathExt is used when PATHEXT is not set in the Linux environment,
+// which is the norm unless it is shared through WSLENV.
+var defaultPathExt = []string{".COM", ".EXE", ".BAT", ".CMD"}
+
+// pathExts returns the extensions from PATHEXT, in order.
+func pathExts() []string {
+	value := os.Getenv("PATHEXT")
+	if value == "" {
+		return defaultPathExt
+	}
+	var exts []string
+	for _, ext := range strings.Split(value, ";") {
+		ext = strings.TrimSpace(ext)
+		if ext == "" {
+			continue
+		}
+		if !strings.HasPrefix(ext, ".") {
+			ext = "." + ext
+		}
+		exts = append(exts, ext)
+	}
+	return exts
+}
+
+// windowsExt returns the lowercase extension of command, which may be a
+// Linux or a Windows path.
+func windowsExt(command string) string {
+	base := command[strings.LastIndexAny(command, `/\`)+1:]
+	if i := strings.LastIndexByte(base, '.'); i > 0 {
+		return strings.ToLower(base[i:])
+	}
+	return ""
+}
+
+// hasRunnableExt reports whether command already names a file Windows can
+// run: an extension from PATHEXT, or .ps1.
+func hasRunnableExt(command string) bool {
+	ext := windowsExt(command)
+	if ext == "" {
+		return false
+	}
+	if ext == ".ps1" {
+		return true
+	}
+	for _, known := range pathExts() {
+		if strings.EqualFold(ext, known) {
+			return true
+		}
+	}
+	return false
+}
+
+// isWindowsPath reports whether p is written as a Windows path, with a
+// drive letter or backslashes, rather than a Linux one.
+func isWindowsPath(p string) bool {
+	if len(p) >= 2 && p[1] == ':' && (p[0]|0x20 >= 'a' && p[0]|0x20 <= 'z') {
+		return true
+	}
+	return strings.Contains(p, `\`)
+}
+
+// Resolution describes how a command name was resolved to a file.
+type Resolution struct {
+	// Command is the command as given.
+	Command string
+
+	// Name is the command to run: Command with the extension that matched.
+	Name string
+
+	// Path is the Linux path of the file. It is empty for a command given
+	// as a Windows path, which is used as is.
+	Path string
+
+	// Via describes how the file was found, e.g. "PATH, PATHEXT .EXE".
+	Via string
+}
+
+// commandCache memoizes resolutions of bare command names, which need a
+// stat per PATH entry and extension, many of them slow 9p mounts.
+var commandCache resolveCache
+
+// resolveCache maps command names to resolutions made under one PATH and
+// PATHEXT. It starts over whenever either changes.
+type resolveCache struct {
+	mu      sync.Mutex
+	env     string
+	entries map[string]Resolution
+}
+
+func (c *resolveCache) get(env, command string) (Resolution, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.env != env {
+		return Resolution{}, false
+	}
+	r, ok := c.entries[command]
+	return r, ok
+}
+
+func (c *resolveCache) put(env, command string, r Resolution) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.env != env || c.entries == nil {
+		c.env = env
+		c.entries = make(map[string]Resolution)
+	}
+	c.entries[command] = r
+}
+
+// ClearCommandCache drops all cached command resolutions. Call it after
+// installing or removing Windows programs while the process is running.
+func ClearCommandCache() {
+	commandCache.mu.Lock()
+	defer commandCache.mu.Unlock()
+	commandCache.env = ""
+	commandCache.entries = nil
+}
+
+// Which resolves command as Execute would, relative to the current
+// directory. It returns a *NotFoundError if no file matches.
+func Which(command string) (Resolution, error) {
+	return resolveCommand(command, "")
+}
+
+// resolveCommand finds command the way Windows does: a name with a
+// runnable extension is looked up as is; otherwise each PATHEXT extension
+// is tried in order. Bare names are searched on PATH, paths relative to
+// workDir; like exec.LookPath, the current directory is not searched for
+// bare names. Windows paths are used as given. Lookups of bare names are
+// cached per PATH and PATHEXT.
+func resolveCommand(command, workDir string) (Resolution, error) {
+	if isWindowsPath(command) {
+		r := Resolution{Command: command, Name: command, Via: "Windows path"}
+		if p, err := wslpath.ToLinuxPath(command); err == nil {
+			if p, ok := statIn("", p); ok {
+				r.Path = p
+			}
+		}
+		return r, nil
+	}
+	if command == "" {
+		return Resolution{}, &NotFoundError{Command: command}
+	}
+
+	bare := !strings.Contains(command, "/")
+	env := os.Getenv("PATH") + "\x00" + os.Getenv("PATHEXT")
+	if bare {
+		if r, ok := commandCache.get(env, command); ok {
+			return r, nil
+		}
+	}
+
+	dirs := []string{workDir}
+	via := "working directory"
+	if bare {
+		dirs = searchPath()
+		via = "PATH"
+	}
+
+	r, ok := lookupCommand(command, dirs, via)
+	if !ok {
+		notFound := &NotFoundError{Command: command, Searched: dirs}
+		if !hasRunnableExt(command) {
+			notFound.Extensions = pathExts()
+		}
+		if bare {
+			if _, ok := lookupCommand(command, []string{workDir}, via); ok {
+				notFound.Hint = "use ./" + command + " to run it from the current directory"
+			}
+		}
+		return Resolution{}, notFound
+	}
+	if bare {
+		commandCache.put(env, command, r)
+	}
+	return r, nil
+}
+
+// lookupCommand looks for command, with the PATHEXT extensions unless it
+// already has a runnable one, in each of dirs in turn.
+func lookupCommand(command string, dirs []string, via string) (Resolution, bool) {
+	var exts []string
+	if !hasRunnableExt(command) {
+		exts = pathExts()
+	}
+	for _, dir := range dirs {
+		if len(exts) == 0 {
+			if p, ok := statIn(dir, command); ok {
+				return Resolution{Command: command, Name: command, Path: p, Via: via}, true
+			}
+			continue
+		}
+		for _, ext := range exts {
+			candidates := []string{command + strings.ToLower(ext)}
+			if ext != strings.ToLower(ext) {
+				candidates = append(candidates, command+ext)
+			}
+			for _, candidate := range candidates {
+				if p, ok := statIn(dir, candidate); ok {
+					return Resolution{
+						Command: command,
+						Name:    candidate,
+						Path:    p,
+						Via:     via + ", PATHEXT " + ext,
+					}, true
+				}
+			}
+		}
+	}
+	return Resolution{}, false
+}
+
+// searchPath returns the non-empty directories on PATH.
+func searchPath() []string {
+	var dirs []string
+	for _, dir := range filepath.SplitList(os.Getenv("PATH")) {
+		if dir != "" {
+			dirs = append(dirs, dir)
+		}
+	}
+	return dirs
+}
+
+// statIn returns the absolute path of name within dir, if it is a regular
+// file. It need not be executable.
+func statIn(dir, name string) (string, bool) {
+	p := name
+	if !filepath.IsAbs(p) && dir != "" {
+		p = filepath.Join(dir, p)
+	}
+	p, err := filepath.Abs(p)
+	if err != nil {
+		return "", false
+	}
+	info, err := os.Stat(p)
+	return p, err == nil && info.Mode().IsRegular()
+}
+
+// scriptHost returns the program and leading arguments that run the
+// resolved command if it is a batch file or PowerShell script:
+// "cmd.exe /d /s /c <script>" or "powershell.exe -File <script>"; see
+// batchArgs for how the cmd.exe line is completed. The script path is
+// translated to a Windows path, since the host cannot open a Linux
+// one. For any other command it returns an empty host.
+func scriptHost(r Resolution, config CommandConfig) (string, []string, error) {
+	ext := windowsExt(r.Name)
+	if ext != ".bat" && ext != ".cmd" && ext != ".ps1" {
+		return "", nil, nil
+	}
+
+	script := r.Name
+	if r.Path != "" {
+		var err error
+		if script, err = wslpath.ToWindowsPath(r.Path); err != nil {
+			return "", nil, fmt.Errorf("failed to translate script path %q: %w", r.Path, err)
+		}
+	}
+
+	if ext == ".ps1" {
+		args := []string{"-NoProfile"}
+		if !config.Interactive {
+			args = append(args, "-NonInteractive")
+		}
+		// Scripts under \\wsl.localhost count as remote and would be
+		// refused by the default RemoteSigned policy.
+		args = append(args, "-ExecutionPolicy", "Bypass", "-File", script)
+		return defaultPowerShell(), args, nil
+	}
+	return "cmd.exe", []string{"/d", "/s", "/c", script}, nil
+}
+
+// isBatchFile reports whether command names a batch file.
+func isBatchFile(command string) bool {
+	ext := windowsExt(command)
+	return ext == ".bat" || ext == ".cmd"
+}
@@ -0,0 +1,196 @@
+package bridge
+
+import (
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// fakeWindowsPath creates an empty file for each name in a temporary
+// directory and makes it the only entry on PATH.
+func fakeWindowsPath(t *testing.T, names ...string) string {
+	t.Helper()
+	dir := t.TempDir()
+	for _, name := range names {
+		if err := os.WriteFile(filepath.Join(dir, name), nil, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	t.Setenv("PATH", dir)
+	t.Setenv("PATHEXT", "")
+	return dir
+}
+
+func TestResolveCommand(t *testing.T) {
+	dir := fakeWindowsPath(t, "tool.exe", "build.cmd", "build.bat", "deploy.ps1", "both.cmd", "both.exe", "cmd.exe")
+
+	tests := []struct {
+		name  string
+		input string
+		want  string
+		via   string
+	}{
+		{"already has .exe", "cmd.exe", "cmd.exe", "PATH"},
+		{"exe on PATH", "tool", "tool.exe", "PATH, PATHEXT .EXE"},
+		{"batch on PATH", "build", "build.bat", "PATH, PATHEXT .BAT"},
+		{"PATHEXT order", "both", "both.exe", "PATH, PATHEXT .EXE"},
+		{"explicit ps1", "deploy.ps1", "deploy.ps1", "PATH"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ClearCommandCache()
+			got, err := resolveCommand(tt.input, "")
+			if err != nil {
+				t.Fatalf("resolveCommand(%q) error: %v", tt.input, err)
+			}
+			if got.Name != tt.want || got.Via != tt.via || got.Path != filepath.Join(dir, tt.want) {
+				t.Errorf("resolveCommand(%q) = %+v, want %s via %s", tt.input, got, tt.want, tt.via)
+			}
+		})
+	}
+}
+
+func TestResolveCommand_NotFound(t *testing.T) {
+	dir := fakeWindowsPath(t, "deploy.ps1")
+	ClearCommandCache()
+
+	for _, command := range []string{"nonexistent_binary_xyz", "deploy", "CMD.EXE", "./missing"} {
+		_, err := resolveCommand(command, "")
+		var notFound *NotFoundError
+		if !errors.As(err, &notFound) || !errors.Is(err, exec.ErrNotFound) {
+			t.Errorf("resolveCommand(%q) error = %v, want *NotFoundError", command, err)
+		}
+	}
+
+	_, err := resolveCommand("nonexistent_binary_xyz", "")
+	want := `command "nonexistent_binary_xyz" not found (tried .COM, .EXE, .BAT, .CMD) in ` + dir
+	if err == nil || err.Error() != want {
+		t.Errorf("error = %v, want %s", err, want)
+	}
+}
+
+func TestResolveCommand_PATHEXT(t *testing.T) {
+	fakeWindowsPath(t, "both.cmd", "both.exe", "run.PS1")
+	t.Setenv("PATHEXT", ".CMD;.EXE;PS1")
+
+	if got, _ := resolveCommand("both", ""); got.Name != "both.cmd" {
+		t.Errorf("resolveCommand(both) = %q, want both.cmd", got.Name)
+	}
+	if got, _ := resolveCommand("run", ""); got.Name != "run.PS1" {
+		t.Errorf("resolveCommand(run) = %q, want run.PS1", got.Name)
+	}
+}
+
+func TestResolveCommand_RelativeToWorkDir(t *testing.T) {
+	dir := fakeWindowsPath(t)
+	if err := os.WriteFile(filepath.Join(dir, "make.cmd"), nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	got, err := resolveCommand("./make", dir)
+	if err != nil || got.Name != "./make.cmd" || got.Path != filepath.Join(dir, "make.cmd") {
+		t.Errorf("resolveCommand(./make) = %+v, %v", got, err)
+	}
+
+	// Bare names are not looked up in the working directory.
+	t.Setenv("PATH", "")
+	_, err = resolveCommand("make", dir)
+	var notFound *NotFoundError
+	if !errors.As(err, &notFound) || !strings.Contains(notFound.Hint, "./make") {
+		t.Errorf("resolveCommand(make) error = %v, want a hint to use ./make", err)
+	}
+}
+
+func TestResolveCommand_Cache(t *testing.T) {
+	dir := fakeWindowsPath(t, "tool.exe")
+	ClearCommandCache()
+
+	if _, err := resolveCommand("tool", ""); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Remove(filepath.Join(dir, "tool.exe")); err != nil {
+		t.Fatal(err)
+	}
+	if got, err := resolveCommand("tool", ""); err != nil || got.Name != "tool.exe" {
+		t.Errorf("cached resolveCommand(tool) = %+v, %v; want the cached result", got, err)
+	}
+
+	// A different PATH starts over.
+	other := fakeWindowsPath(t, "tool.cmd")
+	got, err := resolveCommand("tool", "")
+	if err != nil || got.Path != filepath.Join(other, "tool.cmd") {
+		t.Errorf("resolveCommand(tool) after PATH change = %+v, %v", got, err)
+	}
+
+	os.Remove(filepath.Join(other, "tool.cmd"))
+	ClearCommandCache()
+	if _, err := resolveCommand("tool", ""); err == nil {
+		t.Error("resolveCommand(tool) succeeded after ClearCommandCache")
+	}
+}
+
+func TestScriptHost(t *testing.T) {
+	t.Setenv("WSL_DISTRO_NAME", "Ubuntu")
+	dir := fakeWindowsPath(t, "build.cmd", "deploy.ps1", "tool.exe")
+	resolve := func(command, workDir string) Resolution {
+		t.Helper()
+		r, err := resolveCommand(command, workDir)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return r
+	}
+
+	host, args, err := scriptHost(resolve("build.cmd", ""), CommandConfig{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if host != "cmd.exe" || !reflect.DeepEqual(args[:3], []string{"/d", "/s", "/c"}) || len(args) != 4 {
+		t.Fatalf("scriptHost(build.cmd) = %q %q", host, args)
+	}
+	if !strings.HasPrefix(args[3], `\\`) || !strings.HasSuffix(args[3], `\build.cmd`) {
+		t.Errorf("script path = %q, want a Windows path to build.cmd", args[3])
+	}
+
+	host, args, err = scriptHost(resolve("./deploy.ps1", dir), CommandConfig{WorkDir: dir})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"-NoProfile", "-NonInteractive", "-ExecutionPolicy", "Bypass", "-File"}
+	if !strings.HasSuffix(host, ".exe") || !reflect.DeepEqual(args[:len(want)], want) {
+		t.Errorf("scriptHost(deploy.ps1) = %q %q", host, args)
+	}
+
+	_, args, err = scriptHost(resolve("./deploy.ps1", dir), CommandConfig{WorkDir: dir, Interactive: true})
+	if err != nil || args[1] == "-NonInteractive" {
+		t.Errorf("interactive scriptHost(deploy.ps1) = %q, %v", args, err)
+	}
+
+	if host, args, err := scriptHost(resolve(`Q:\ci\setup.bat`, ""), CommandConfig{}); err != nil || host != "cmd.exe" || args[3] != `Q:\ci\setup.bat` {
+		t.Errorf(`scriptHost(Q:\ci\setup.bat) = %q %q, %v`, host, args, err)
+	}
+
+	if host, args, err := scriptHost(resolve("tool.exe", ""), CommandConfig{}); err != nil || host != "" || args != nil {
+		t.Errorf("scriptHost(tool.exe) = %q %q, %v; want no host", host, args, err)
+	}
+}
+
+func TestWindowsExt(t *testing.T) {
+	tests := map[string]string{
+		"build.CMD":            ".cmd",
+		"./dir.d/run":          "",
+		`C:\a.b\deploy.ps1`:    ".ps1",
+		".hidden":              "",
+		"/mnt/c/tools/x.Exe":   ".exe",
+		"archive.tar.gz":       ".gz",
+		`\\server\share\a.bat`: ".bat",
+	}
+	for in, want := range tests {
+		if got := windowsExt(in); got != want {
+			t.Errorf("windowsExt(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
@@ -0,0 +1,289 @@
+package bridge
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os/exec"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ErrExpectTimeout is returned by Session.Expect when the pattern does not
+// appear in the output before the timeout elapses.
+var ErrExpectTimeout = errors.New("gowinbridge: timed out waiting for expected output")
+
+// sessionCloseTimeout is how long Close waits for the command to exit after
+// its stdin is closed before killing it and its Windows process tree.
+const sessionCloseTimeout = 5 * time.Second
+
+// Session is a running command driven programmatically, expect-style:
+// write input, wait for output matching a pattern, repeat. Stdout and
+// stderr are decoded with config.Encoding and merged into a single view,
+// since prompts may appear on either stream.
+//
+// A Session is safe for concurrent use, but Expect calls should not be
+// interleaved: each one consumes the output up to the end of its match.
+type Session struct {
+	cmd     *command
+	config  CommandConfig
+	parent  context.Context
+	execCtx context.Context
+	cancel  context.CancelFunc
+	stdin   io.WriteCloser
+	pipes   [2]io.Closer
+	start   time.Time
+
+	mu     sync.Mutex
+	seen   strings.Builder // merged output of both streams
+	stdout strings.Builder
+	stderr strings.Builder
+	offset int           // end of the last Expect match within seen
+	notify chan struct{} // closed and replaced whenever output arrives
+	eof    bool          // both streams have ended
+	killed bool          // Close gave up and killed the command
+
+	readers  sync.WaitGroup
+	readErrs [2]error
+
+	waitOnce sync.Once
+	waitDone chan struct{}
+	output   Output
+	waitErr  error
+}
+
+// Start launches the command described by config and returns a Session
+// for driving it. Interactive, OnOutput and the capture options of
+// CommandConfig do not apply to sessions; config.Stdin, if set, is ignored
+// in favour of Session.Write.
+//
+// config.Timeout bounds the whole session. The caller must call Close or
+// Wait to release the process.
+func Start(ctx context.Context, config CommandConfig) (*Session, error) {
+	cmd, execCtx, cancel, err := buildCommand(ctx, config)
+	if err != nil {
+		return nil, err
+	}
+	s, err := startSession(cmd, config)
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	s.parent, s.execCtx, s.cancel = ctx, execCtx, cancel
+	return s, nil
+}
+
+// startSession wires up pipes and decoders for cmd and starts it.
+func startSession(cmd *command, config CommandConfig) (*Session, error) {
+	startErr := func(err error) error {
+		return &StartError{Command: config.Command, Err: err}
+	}
+
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, startErr(fmt.Errorf("failed to create stdin pipe: %w", err))
+	}
+	stdoutPipe, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, startErr(fmt.Errorf("failed to create stdout pipe: %w", err))
+	}
+	stderrPipe, err := cmd.StderrPipe()
+	if err != nil {
+		return nil, startErr(fmt.Errorf("failed to create stderr pipe: %w", err))
+	}
+
+	stdoutReader, err := NewDecodingReader(stdoutPipe, config.Encoding)
+	if err != nil {
+		return nil, startErr(fmt.Errorf("failed to create stdout decoder: %w", err))
+	}
+	stderrReader, err := NewDecodingReader(stderrPipe, config.Encoding)
+	if err != nil {
+		return nil, startErr(fmt.Errorf("failed to create stderr decoder: %w", err))
+	}
+
+	s := &Session{
+		cmd:      cmd,
+		config:   config,
+		parent:   context.Background(),
+		execCtx:  context.Background(),
+		cancel:   func() {},
+		stdin:    stdin,
+		pipes:    [2]io.Closer{stdoutPipe, stderrPipe},
+		notify:   make(chan struct{}),
+		waitDone: make(chan struct{}),
+	}
+
+	s.start = time.Now()
+	if err := cmd.Start(); err != nil {
+		return nil, startErr(err)
+	}
+
+	s.readers.Add(2)
+	go s.read(0, stdoutReader, stdoutPipe, &s.stdout)
+	go s.read(1, stderrReader, stderrPipe, &s.stderr)
+	go func() {
+		s.readers.Wait()
+		s.mu.Lock()
+		s.eof = true
+		s.broadcast()
+		s.mu.Unlock()
+	}()
+
+	return s, nil
+}
+
+// read copies decoded chunks from r into the session until EOF. Chunks,
+// not lines, are used so that prompts without a newline are visible.
+func (s *Session) read(idx int, r, pipe io.Reader, stream *strings.Builder) {
+	defer s.readers.Done()
+	buf := make([]byte, 32*1024)
+	for {
+		n, err := r.Read(buf)
+		if n > 0 {
+			s.mu.Lock()
+			s.seen.Write(buf[:n])
+			stream.Write(buf[:n])
+			s.broadcast()
+			s.mu.Unlock()
+		}
+		if err == io.EOF {
+			return
+		}
+		if err != nil {
+			s.mu.Lock()
+			killed := s.killed
+			s.mu.Unlock()
+			if !killed {
+				io.Copy(io.Discard, pipe)
+				s.readErrs[idx] = err
+			}
+			return
+		}
+	}
+}
+
+// broadcast wakes up every pending Expect. The caller must hold s.mu.
+func (s *Session) broadcast() {
+	close(s.notify)
+	s.notify = make(chan struct{})
+}
+
+// Write sends p to the command's stdin.
+func (s *Session) Write(p []byte) (int, error) {
+	return s.stdin.Write(p)
+}
+
+// SendLine writes line followed by CRLF, the line ending Windows console
+// programs expect.
+func (s *Session) SendLine(line string) error {
+	_, err := io.WriteString(s.stdin, line+"\r\n")
+	return err
+}
+
+// Expect waits until output not yet consumed by a previous Expect matches
+// re, and returns the matched text. The output up to the end of the match
+// is consumed. It returns ErrExpectTimeout if no match appears within
+// timeout (zero means wait indefinitely), and io.EOF if the command's
+// output ends first.
+func (s *Session) Expect(re *regexp.Regexp, timeout time.Duration) (string, error) {
+	var deadline <-chan time.Time
+	if timeout > 0 {
+		timer := time.NewTimer(timeout)
+		defer timer.Stop()
+		deadline = timer.C
+	}
+
+	for {
+		s.mu.Lock()
+		pending := s.seen.String()[s.offset:]
+		if loc := re.FindStringIndex(pending); loc != nil {
+			s.offset += loc[1]
+			s.mu.Unlock()
+			return pending[loc[0]:loc[1]], nil
+		}
+		eof, notify := s.eof, s.notify
+		s.mu.Unlock()
+
+		if eof {
+			return "", io.EOF
+		}
+		select {
+		case <-notify:
+		case <-deadline:
+			return "", fmt.Errorf("%w: %s", ErrExpectTimeout, re)
+		}
+	}
+}
+
+// Output returns everything the command has written so far, stdout and
+// stderr merged in arrival order, including output already consumed by
+// Expect.
+func (s *Session) Output() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.seen.String()
+}
+
+// Wait waits for the command to exit and returns its Output. Stdin is left
+// open; use Close to signal end of input. Like Execute, it returns an
+// *ExitError for a non-zero exit code and ErrTimeout or ErrCanceled if the
+// session's context ended it. Wait may be called repeatedly.
+func (s *Session) Wait() (Output, error) {
+	s.waitOnce.Do(func() {
+		s.readers.Wait()
+		waitErr := s.cmd.Wait()
+
+		s.mu.Lock()
+		output := Output{
+			Stdout:   normalizeNewlines(s.stdout.String(), NewlineDefault),
+			Stderr:   normalizeNewlines(s.stderr.String(), NewlineDefault),
+			Duration: time.Since(s.start),
+		}
+		s.mu.Unlock()
+
+		var err error
+		if waitErr != nil {
+			if exitErr, ok := waitErr.(*exec.ExitError); ok {
+				output.ExitCode = exitErr.ExitCode()
+			} else {
+				err = fmt.Errorf("command execution failed: %w", waitErr)
+			}
+		}
+		if err == nil {
+			if readErr := errors.Join(s.readErrs[0], s.readErrs[1]); readErr != nil {
+				err = fmt.Errorf("output capture failed: %w", readErr)
+			}
+		}
+		s.output, s.waitErr = classifyResult(s.parent, s.execCtx, s.config, output, err)
+		s.cancel()
+		close(s.waitDone)
+	})
+	return s.output, s.waitErr
+}
+
+// Close closes the command's stdin and waits for it to exit. If it has not
+// exited within a few seconds, it is killed, along with its Windows process
+// tree as config.Termination allows, and any output still in flight is
+// discarded.
+func (s *Session) Close() error {
+	s.stdin.Close()
+
+	go s.Wait()
+	select {
+	case <-s.waitDone:
+	case <-time.After(sessionCloseTimeout):
+		s.mu.Lock()
+		s.killed = true
+		s.mu.Unlock()
+		s.cmd.kill()
+		// Grandchildren may still hold the pipes open; stop reading them.
+		s.pipes[0].Close()
+		s.pipes[1].Close()
+		<-s.waitDone
+	}
+	_, err := s.Wait()
+	return err
+}
@@ -0,0 +1,133 @@
+package bridge
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os/exec"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+)
+
+func startShellSession(t *testing.T, script string, config CommandConfig) *Session {
+	t.Helper()
+	if _, err := exec.LookPath("sh"); err != nil {
+		t.Skip("sh not available")
+	}
+	config.Command = "sh"
+	s, err := startSession(&command{Cmd: exec.Command("sh", "-c", script)}, config)
+	if err != nil {
+		t.Fatalf("startSession: %v", err)
+	}
+	t.Cleanup(func() { s.Close() })
+	return s
+}
+
+func TestSession_PromptAndReply(t *testing.T) {
+	// The prompt has no trailing newline, like most REPL prompts.
+	s := startShellSession(t, `printf 'name? '; read n; echo "hello $n"; printf '> '; read x; echo "bye" >&2`, CommandConfig{})
+
+	if _, err := s.Expect(regexp.MustCompile(`name\? $`), time.Second); err != nil {
+		t.Fatalf("waiting for prompt: %v", err)
+	}
+	if err := s.SendLine("gopher"); err != nil {
+		t.Fatalf("SendLine: %v", err)
+	}
+	got, err := s.Expect(regexp.MustCompile(`hello \w+`), time.Second)
+	if err != nil {
+		t.Fatalf("waiting for greeting: %v", err)
+	}
+	if got != "hello gopher" {
+		t.Errorf("match = %q, want %q", got, "hello gopher")
+	}
+
+	if _, err := s.Expect(regexp.MustCompile(`> `), time.Second); err != nil {
+		t.Fatalf("waiting for second prompt: %v", err)
+	}
+	s.Write([]byte("x\n"))
+	// Stderr is part of the merged view.
+	if _, err := s.Expect(regexp.MustCompile(`bye`), time.Second); err != nil {
+		t.Fatalf("waiting for stderr output: %v", err)
+	}
+
+	if err := s.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+	out, err := s.Wait()
+	if err != nil {
+		t.Fatalf("Wait: %v", err)
+	}
+	if out.Stderr != "bye" {
+		t.Errorf("Stderr = %q, want %q", out.Stderr, "bye")
+	}
+	if !strings.Contains(s.Output(), "hello gopher\r") {
+		t.Errorf("Output() = %q, want the full transcript", s.Output())
+	}
+}
+
+func TestSession_ExpectConsumesMatches(t *testing.T) {
+	s := startShellSession(t, `echo ready; echo ready; sleep 1`, CommandConfig{})
+
+	re := regexp.MustCompile(`ready`)
+	for i := 0; i < 2; i++ {
+		if _, err := s.Expect(re, time.Second); err != nil {
+			t.Fatalf("match %d: %v", i, err)
+		}
+	}
+	// Both occurrences are consumed; a third must time out.
+	_, err := s.Expect(re, 50*time.Millisecond)
+	if !errors.Is(err, ErrExpectTimeout) {
+		t.Errorf("third Expect error = %v, want ErrExpectTimeout", err)
+	}
+}
+
+func TestSession_ExpectEOF(t *testing.T) {
+	s := startShellSession(t, `echo done`, CommandConfig{})
+
+	_, err := s.Expect(regexp.MustCompile(`never`), 5*time.Second)
+	if err != io.EOF {
+		t.Errorf("Expect error = %v, want io.EOF", err)
+	}
+}
+
+func TestSession_Decoding(t *testing.T) {
+	// "café>" in CP1252 must match a UTF-8 pattern.
+	s := startShellSession(t, `printf 'caf\351>'; read x`, CommandConfig{Encoding: EncodingCP1252})
+
+	if _, err := s.Expect(regexp.MustCompile(`café>`), time.Second); err != nil {
+		t.Fatalf("decoded prompt not matched: %v (seen %q)", err, s.Output())
+	}
+}
+
+func TestSession_CloseKillsStuckCommand(t *testing.T) {
+	if testing.Short() {
+		t.Skip("waits for the close timeout")
+	}
+	if _, err := exec.LookPath("sh"); err != nil {
+		t.Skip("sh not available")
+	}
+	// Ignores EOF on stdin, and the sleep grandchild keeps the pipes open;
+	// Close must fall back to killing it, Windows process tree included.
+	fake := &fakeTreeKiller{}
+	cmd := &command{Cmd: exec.CommandContext(context.Background(), "sh", "-c", `sleep 30; echo late`)}
+	cmd.term = installTermination(cmd.Cmd, fake.policy(0))
+	s, err := startSession(cmd, CommandConfig{Command: "sh"})
+	if err != nil {
+		t.Fatalf("startSession: %v", err)
+	}
+
+	start := time.Now()
+	s.Close()
+	if elapsed := time.Since(start); elapsed > sessionCloseTimeout+5*time.Second {
+		t.Errorf("Close took %s", elapsed)
+	}
+	out, _ := s.Wait()
+	if out.ExitCode == 0 {
+		t.Error("killed command reported exit code 0")
+	}
+	if _, killed := fake.calls(); len(killed) != 1 || killed[0] != 4242 {
+		t.Errorf("KillTree calls = %v, want [4242]", killed)
+	}
+}
@@ -0,0 +1,389 @@
+package bridge
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
+)
+
+// DefaultGracePeriod is how long a canceled command is given to exit after
+// being interrupted before its Windows process tree is killed.
+const DefaultGracePeriod = 5 * time.Second
+
+// treeKillTimeout bounds PID discovery, and separately the tree kill.
+const treeKillTimeout = 15 * time.Second
+
+// pidClockSkew allows for the Windows and WSL clocks disagreeing slightly
+// when matching process creation times.
+const pidClockSkew = 2 * time.Second
+
+// pidStartWindow is how long after the interop process starts its Windows
+// process may be created and still match the lookup. Commands of the same
+// name started further apart than this are told apart.
+const pidStartWindow = 10 * time.Second
+
+// The Windows process appears shortly after the interop process starts, so
+// a lookup at start that finds nothing is retried a few times.
+const (
+	pidLookupAttempts = 5
+	pidLookupInterval = 200 * time.Millisecond
+)
+
+// errNoWindowsProcess is returned by the PID lookup when no process matches.
+var errNoWindowsProcess = errors.New("no windows process found")
+
+// TerminationPolicy controls how a command is stopped when its context is
+// canceled or its Timeout elapses.
+//
+// Killing the Linux-side interop process alone leaves processes spawned by
+// cmd.exe or powershell.exe running on Windows, so by default the command
+// is first interrupted (SIGINT, which WSL relays as Ctrl+C), then given
+// GracePeriod to exit, and finally its Windows process tree is killed with
+// "taskkill.exe /T /F". The Windows PID is looked up when the context
+// ends, just before the interrupt, so the tree is still killed if the root
+// process exits on the interrupt and leaves children behind; in that case
+// the kill happens as soon as the root exits. A command whose context
+// never ends costs no lookup.
+type TerminationPolicy struct {
+	// GracePeriod is how long to wait after the interrupt before killing
+	// the process tree. Zero means DefaultGracePeriod; a negative value
+	// skips the interrupt and kills immediately.
+	GracePeriod time.Duration
+
+	// NoTreeKill disables the Windows process tree kill, so only the
+	// Linux-side interop process is killed once the grace period expires.
+	NoTreeKill bool
+
+	// LookupAtStart looks up the Windows PID as soon as the command
+	// starts instead of when its context ends, for commands whose root
+	// may exit before then and leave children running. It costs one
+	// PowerShell query per command.
+	LookupAtStart bool
+
+	// FindPID locates the Windows PID of the process started from image
+	// (e.g. "cmd.exe") shortly after started. If nil, the process is
+	// looked up with PowerShell; if the lookup is ambiguous, as with
+	// commands of the same name started within seconds of each other, no
+	// tree kill is done.
+	FindPID func(ctx context.Context, image string, started time.Time) (int, error)
+
+	// KillTree kills the Windows process tree rooted at pid. If nil,
+	// "taskkill.exe /T /F /PID <pid>" is run. Since taskkill cannot walk
+	// the tree of a root that has already exited, when it fails the
+	// descendants of pid created since the command started are found by
+	// their recorded parent PIDs with PowerShell and stopped instead.
+	KillTree func(ctx context.Context, pid int) error
+}
+
+// gracePeriod returns the effective grace period.
+func (p TerminationPolicy) gracePeriod() time.Duration {
+	if p.GracePeriod == 0 {
+		return DefaultGracePeriod
+	}
+	return p.GracePeriod
+}
+
+// command is an exec.Cmd together with the terminator enforcing its
+// TerminationPolicy. A nil term means the command is simply killed.
+type command struct {
+	*exec.Cmd
+	term *terminator
+}
+
+// Start starts the command, and the lookup of its Windows PID if the
+// policy asks for it at start.
+func (c *command) Start() error {
+	if err := c.Cmd.Start(); err != nil {
+		return err
+	}
+	if c.term != nil {
+		c.term.begin()
+	}
+	return nil
+}
+
+// Wait waits for the command to exit. If its context ended it, Wait also
+// waits for the Windows process tree to be killed.
+func (c *command) Wait() error {
+	err := c.Cmd.Wait()
+	if c.term != nil {
+		c.term.exited()
+	}
+	return err
+}
+
+// kill stops the command at once, without a grace period: its Windows
+// process tree is killed as the policy allows, then the Linux-side process.
+func (c *command) kill() {
+	if c.term == nil {
+		c.Process.Kill()
+		return
+	}
+	c.term.terminate()
+}
+
+// terminator carries out a TerminationPolicy for one command.
+type terminator struct {
+	cmd     *exec.Cmd
+	policy  TerminationPolicy
+	started time.Time
+
+	// The PID lookup is started at most once, by lookup, and closes
+	// pidDone once pid and pidErr are set.
+	lookupOnce sync.Once
+	pidDone    chan struct{}
+	pid        int
+	pidErr     error
+
+	canceled   atomic.Bool // the context ended the command
+	exitedOnce sync.Once
+	exitedCh   chan struct{} // closed when the Linux-side process has exited
+	done       chan struct{} // closed when a cancellation has been carried out
+	once       sync.Once
+}
+
+// installTermination arranges for cmd, whose context was set by
+// exec.CommandContext, to be stopped according to policy once the
+// context is done. It must be called before cmd is started.
+func installTermination(cmd *exec.Cmd, policy TerminationPolicy) *terminator {
+	t := &terminator{
+		cmd:      cmd,
+		policy:   policy,
+		started:  time.Now(),
+		pidDone:  make(chan struct{}),
+		exitedCh: make(chan struct{}),
+		done:     make(chan struct{}),
+	}
+	cmd.Cancel = func() error {
+		t.canceled.Store(true)
+		if !policy.NoTreeKill {
+			// Find the root while it still runs: it may exit on the
+			// interrupt and leave its children behind.
+			<-t.lookup()
+		}
+		grace := policy.gracePeriod()
+		if grace < 0 {
+			t.terminate()
+			close(t.done)
+			return nil
+		}
+		err := cmd.Process.Signal(os.Interrupt)
+		go func() {
+			timer := time.NewTimer(grace)
+			defer timer.Stop()
+			select {
+			case <-timer.C:
+			case <-t.exitedCh:
+			}
+			t.terminate()
+			close(t.done)
+		}()
+		return err
+	}
+	return t
+}
+
+// begin is called once the command has started, and starts the PID lookup
+// if the policy wants it at start.
+func (t *terminator) begin() {
+	if t.policy.LookupAtStart && !t.policy.NoTreeKill {
+		t.lookup()
+	}
+}
+
+// lookup starts the Windows PID lookup unless it has already been started,
+// and returns a channel closed when it has finished. A lookup that finds
+// nothing is retried while the command runs, since the Windows process
+// appears a little after the interop process.
+func (t *terminator) lookup() <-chan struct{} {
+	t.lookupOnce.Do(func() {
+		go func() {
+			defer close(t.pidDone)
+			ctx, cancel := context.WithTimeout(context.Background(), treeKillTimeout)
+			defer cancel()
+			image := filepath.Base(t.cmd.Path)
+			for attempt := 1; ; attempt++ {
+				t.pid, t.pidErr = findPID(ctx, t.policy, image, t.started)
+				if !errors.Is(t.pidErr, errNoWindowsProcess) || attempt == pidLookupAttempts {
+					return
+				}
+				select {
+				case <-time.After(pidLookupInterval):
+				case <-t.exitedCh:
+					return
+				case <-ctx.Done():
+					return
+				}
+			}
+		}()
+	})
+	return t.pidDone
+}
+
+// exited records that the Linux-side process has been reaped. After a
+// cancellation, it waits for the tree kill to finish.
+func (t *terminator) exited() {
+	t.exitedOnce.Do(func() { close(t.exitedCh) })
+	if t.canceled.Load() {
+		<-t.done
+	}
+}
+
+// terminate kills the Windows process tree of the command, using the PID
+// found while the root ran if there is one, and then the Linux-side
+// process if it is still running. Only the first call has any effect.
+func (t *terminator) terminate() {
+	t.once.Do(func() {
+		if !t.policy.NoTreeKill {
+			ctx, cancel := context.WithTimeout(context.Background(), treeKillTimeout)
+			defer cancel()
+			select {
+			case <-t.lookup():
+				if t.pidErr == nil {
+					killTree(ctx, t.policy, t.pid, t.started)
+				}
+			case <-ctx.Done():
+			}
+		}
+		if processAlive(t.cmd.Process) {
+			t.cmd.Process.Kill()
+		}
+	})
+}
+
+// processAlive reports whether p has not yet been reaped.
+func processAlive(p *os.Process) bool {
+	return p.Signal(syscall.Signal(0)) == nil
+}
+
+// findPID dispatches to policy.FindPID or the PowerShell lookup.
+func findPID(ctx context.Context, policy TerminationPolicy, image string, started time.Time) (int, error) {
+	if policy.FindPID != nil {
+		return policy.FindPID(ctx, image, started)
+	}
+	return findWindowsPID(ctx, image, started)
+}
+
+// killTree dispatches to policy.KillTree or taskkill.exe, falling back to
+// the PowerShell tree walk when taskkill fails.
+func killTree(ctx context.Context, policy TerminationPolicy, pid int, started time.Time) error {
+	if policy.KillTree != nil {
+		return policy.KillTree(ctx, pid)
+	}
+	err := taskkill(ctx, pid)
+	if err == nil {
+		return nil
+	}
+	if walkErr := killWindowsTree(ctx, pid, started); walkErr != nil {
+		return errors.Join(err, walkErr)
+	}
+	return nil
+}
+
+// taskkill forcibly terminates the Windows process tree rooted at pid.
+func taskkill(ctx context.Context, pid int) error {
+	out, err := exec.CommandContext(ctx, "taskkill.exe", "/T", "/F", "/PID", strconv.Itoa(pid)).CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("taskkill.exe /PID %d failed: %w: %s", pid, err, strings.TrimSpace(string(out)))
+	}
+	return nil
+}
+
+// killWindowsTree forcibly stops pid and every process descended from it
+// that was created since started. Unlike "taskkill /T", it follows the
+// parent PIDs recorded by Windows, so it still finds the children of a
+// root that has already exited. The creation time check keeps it from
+// following a reused PID into an unrelated tree.
+func killWindowsTree(ctx context.Context, pid int, started time.Time) error {
+	since := started.Add(-pidClockSkew).UTC().Format(time.RFC3339)
+	script := fmt.Sprintf(
+		`$since = [datetime]::Parse('%s').ToUniversalTime(); `+
+			`$procs = @(Get-CimInstance Win32_Process | Where-Object { $_.CreationDate.ToUniversalTime() -ge $since }); `+
+			`$tree = @(%d); `+
+			`for ($i = 0; $i -lt $tree.Count; $i++) { `+
+			`foreach ($p in $procs) { if ($p.ParentProcessId -eq $tree[$i] -and $tree -notcontains $p.ProcessId) { $tree += $p.ProcessId } } }; `+
+			`$tree | ForEach-Object { Stop-Process -Id $_ -Force -ErrorAction SilentlyContinue }`,
+		since, pid)
+
+	out, err := exec.CommandContext(ctx, "powershell.exe",
+		"-NoProfile", "-NonInteractive", "-EncodedCommand", encodePowerShell(script)).CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("killing windows process tree %d failed: %w: %s", pid, err, strings.TrimSpace(string(out)))
+	}
+	return nil
+}
+
+// findWindowsPID asks Windows for processes named image created within
+// pidStartWindow of started. Exactly one match is required: with concurrent commands of the
+// same name, guessing could kill an unrelated tree. The PowerShell running
+// the query, and its ancestors, are reported as "self" and left out, since
+// for image powershell.exe the query would otherwise find itself.
+func findWindowsPID(ctx context.Context, image string, started time.Time) (int, error) {
+	if strings.ContainsAny(image, `'"`) {
+		return 0, fmt.Errorf("unsupported image name %q", image)
+	}
+	since := started.Add(-pidClockSkew).UTC().Format(time.RFC3339)
+	until := started.Add(pidStartWindow + pidClockSkew).UTC().Format(time.RFC3339)
+	script := fmt.Sprintf(
+		`$since = [datetime]::Parse('%s').ToUniversalTime(); `+
+			`$until = [datetime]::Parse('%s').ToUniversalTime(); `+
+			`$all = @(Get-CimInstance Win32_Process); `+
+			`$p = $PID; `+
+			`for ($i = 0; $p -and $i -lt 64; $i++) { "self $p"; $p = ($all | Where-Object { $_.ProcessId -eq $p }).ParentProcessId }; `+
+			`$all | Where-Object { $_.Name -eq '%s' -and $_.CreationDate.ToUniversalTime() -ge $since -and $_.CreationDate.ToUniversalTime() -le $until } | `+
+			`ForEach-Object { "match $($_.ProcessId)" }`,
+		since, until, image)
+
+	out, err := exec.CommandContext(ctx, "powershell.exe",
+		"-NoProfile", "-NonInteractive", "-EncodedCommand", encodePowerShell(script)).Output()
+	if err != nil {
+		return 0, fmt.Errorf("windows process lookup failed: %w", err)
+	}
+	return parseSinglePID(string(out), image)
+}
+
+// parseSinglePID parses the "self <pid>" and "match <pid>" lines of the
+// lookup and requires exactly one match that is not a self PID.
+func parseSinglePID(out, image string) (int, error) {
+	self := make(map[int]bool)
+	var matches []int
+	for _, line := range strings.Split(strings.TrimSpace(out), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		kind, value, _ := strings.Cut(line, " ")
+		pid, err := strconv.Atoi(value)
+		if err != nil || kind != "self" && kind != "match" {
+			return 0, fmt.Errorf("unexpected process lookup output %q", line)
+		}
+		if kind == "self" {
+			self[pid] = true
+		} else {
+			matches = append(matches, pid)
+		}
+	}
+	var pids []int
+	for _, pid := range matches {
+		if !self[pid] {
+			pids = append(pids, pid)
+		}
+	}
+	switch len(pids) {
+	case 1:
+		return pids[0], nil
+	case 0:
+		return 0, fmt.Errorf("%w for %s", errNoWindowsProcess, image)
+	default:
+		return 0, fmt.Errorf("ambiguous windows process for %s: pids %v", image, pids)
+	}
+}
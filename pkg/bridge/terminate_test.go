@@ -0,0 +1,192 @@
+package bridge
+
+import (
+	"context"
+	"errors"
+	"os/exec"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeTreeKiller records FindPID and KillTree calls instead of looking up
+// and killing Windows processes.
+type fakeTreeKiller struct {
+	mu     sync.Mutex
+	images []string
+	killed []int
+}
+
+func (f *fakeTreeKiller) policy(grace time.Duration) TerminationPolicy {
+	return TerminationPolicy{
+		GracePeriod: grace,
+		FindPID: func(ctx context.Context, image string, started time.Time) (int, error) {
+			f.mu.Lock()
+			defer f.mu.Unlock()
+			f.images = append(f.images, image)
+			return 4242, nil
+		},
+		KillTree: func(ctx context.Context, pid int) error {
+			f.mu.Lock()
+			defer f.mu.Unlock()
+			f.killed = append(f.killed, pid)
+			return nil
+		},
+	}
+}
+
+func (f *fakeTreeKiller) calls() ([]string, []int) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.images, f.killed
+}
+
+func runWithPolicy(t *testing.T, script string, policy TerminationPolicy, after time.Duration) time.Duration {
+	t.Helper()
+	if _, err := exec.LookPath("sh"); err != nil {
+		t.Skip("sh not available")
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), after)
+	defer cancel()
+
+	cmd := &command{Cmd: exec.CommandContext(ctx, "sh", "-c", script)}
+	cmd.term = installTermination(cmd.Cmd, policy)
+
+	start := time.Now()
+	if err := cmd.Start(); err != nil {
+		t.Fatalf("start: %v", err)
+	}
+	cmd.Wait()
+	return time.Since(start)
+}
+
+func TestTermination_TreeKillAfterRootExits(t *testing.T) {
+	fake := &fakeTreeKiller{}
+	// sleep exits on SIGINT, but children it left on Windows would not be
+	// gone with it, so the tree is still killed, without waiting out the
+	// grace period.
+	elapsed := runWithPolicy(t, "exec sleep 10", fake.policy(5*time.Second), 50*time.Millisecond)
+
+	if elapsed > 2*time.Second {
+		t.Errorf("took %s, expected the tree kill right after the root exited", elapsed)
+	}
+	if _, killed := fake.calls(); len(killed) != 1 || killed[0] != 4242 {
+		t.Errorf("KillTree calls = %v, want [4242]", killed)
+	}
+}
+
+func TestTermination_PIDLookedUpWhileRootRuns(t *testing.T) {
+	if _, err := exec.LookPath("sh"); err != nil {
+		t.Skip("sh not available")
+	}
+	for _, atStart := range []bool{false, true} {
+		fake := &fakeTreeKiller{}
+		policy := fake.policy(5 * time.Second)
+		policy.LookupAtStart = atStart
+		var rootAlive bool
+		var lookedUp time.Time
+		var cmd *command
+		findPID := policy.FindPID
+		policy.FindPID = func(ctx context.Context, image string, started time.Time) (int, error) {
+			rootAlive = processAlive(cmd.Process)
+			lookedUp = time.Now()
+			return findPID(ctx, image, started)
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), 300*time.Millisecond)
+		deadline, _ := ctx.Deadline()
+		cmd = &command{Cmd: exec.CommandContext(ctx, "sh", "-c", "exec sleep 10")}
+		cmd.term = installTermination(cmd.Cmd, policy)
+		if err := cmd.Start(); err != nil {
+			t.Fatalf("start: %v", err)
+		}
+		cmd.Wait()
+		cancel()
+
+		if images, killed := fake.calls(); len(images) != 1 || len(killed) != 1 {
+			t.Fatalf("LookupAtStart=%v: FindPID calls = %v, KillTree calls = %v; want one each", atStart, images, killed)
+		}
+		if !rootAlive {
+			t.Errorf("LookupAtStart=%v: PID looked up after the root exited", atStart)
+		}
+		if atStart != lookedUp.Before(deadline) {
+			t.Errorf("LookupAtStart=%v: PID looked up at %s, context ended at %s", atStart, lookedUp, deadline)
+		}
+	}
+}
+
+func TestTermination_NoTreeKillWithoutCancel(t *testing.T) {
+	fake := &fakeTreeKiller{}
+	runWithPolicy(t, "exit 0", fake.policy(5*time.Second), time.Minute)
+
+	if images, killed := fake.calls(); len(images) != 0 || len(killed) != 0 {
+		t.Errorf("FindPID calls = %v, KillTree calls = %v for a command that was not canceled; want none", images, killed)
+	}
+}
+
+func TestTermination_TreeKillAfterGrace(t *testing.T) {
+	fake := &fakeTreeKiller{}
+	// Ignores SIGINT, so it must be killed after the grace period.
+	elapsed := runWithPolicy(t, `trap "" INT; sleep 10`, fake.policy(100*time.Millisecond), 50*time.Millisecond)
+
+	if elapsed > 5*time.Second {
+		t.Errorf("process survived %s, expected kill shortly after the grace period", elapsed)
+	}
+	images, killed := fake.calls()
+	if len(killed) != 1 || killed[0] != 4242 {
+		t.Errorf("KillTree calls = %v, want [4242]", killed)
+	}
+	if len(images) != 1 || images[0] != "sh" {
+		t.Errorf("FindPID images = %v, want [sh]", images)
+	}
+}
+
+func TestTermination_NegativeGraceKillsImmediately(t *testing.T) {
+	fake := &fakeTreeKiller{}
+	elapsed := runWithPolicy(t, `trap "" INT; sleep 10`, fake.policy(-1), 50*time.Millisecond)
+
+	if elapsed > 2*time.Second {
+		t.Errorf("process survived %s, expected an immediate kill", elapsed)
+	}
+	if _, killed := fake.calls(); len(killed) != 1 {
+		t.Errorf("KillTree calls = %v, want one", killed)
+	}
+}
+
+func TestTermination_NoTreeKill(t *testing.T) {
+	fake := &fakeTreeKiller{}
+	policy := fake.policy(50 * time.Millisecond)
+	policy.NoTreeKill = true
+	runWithPolicy(t, `trap "" INT; sleep 10`, policy, 50*time.Millisecond)
+
+	if images, killed := fake.calls(); len(images) != 0 || len(killed) != 0 {
+		t.Errorf("NoTreeKill still looked up (%v) or killed (%v) the tree", images, killed)
+	}
+}
+
+func TestParseSinglePID(t *testing.T) {
+	tests := []struct {
+		image   string
+		out     string
+		want    int
+		wantErr bool
+	}{
+		{"cmd.exe", "self 500\r\nself 40\r\nmatch 1234\r\n", 1234, false},
+		{"cmd.exe", "self 500\r\n", 0, true},
+		{"cmd.exe", "match 12\r\nmatch 34\r\n", 0, true},
+		{"cmd.exe", "Access denied", 0, true},
+		{"cmd.exe", "match x", 0, true},
+		// The lookup's own powershell.exe, and its parent, match too.
+		{"powershell.exe", "self 500\r\nself 40\r\nmatch 40\r\nmatch 500\r\nmatch 1234\r\n", 1234, false},
+		{"powershell.exe", "self 500\r\nmatch 500\r\n", 0, true},
+	}
+	for _, tt := range tests {
+		got, err := parseSinglePID(tt.out, tt.image)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("parseSinglePID(%q, %s) = %d, %v; want %d, err %v", tt.out, tt.image, got, err, tt.want, tt.wantErr)
+		}
+	}
+	if _, err := parseSinglePID("self 500\r\nmatch 500\r\n", "powershell.exe"); !errors.Is(err, errNoWindowsProcess) {
+		t.Errorf("only the lookup itself matched: err = %v, want errNoWindowsProcess", err)
+	}
+}
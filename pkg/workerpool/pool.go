@@ -5,6 +5,7 @@ package workerpool
 
 import (
 	"context"
+	"fmt"
 	"runtime"
 	"sync"
 
@@ -12,7 +13,9 @@ import (
 )
 
 // Result wraps the output of a command execution along with the
-// original config that produced it.
+// original config that produced it. Err carries the typed errors of
+// package bridge (ErrTimeout, ErrCanceled, *ExitError, ...), so callers
+// can decide on retries with errors.Is and errors.As.
 type Result struct {
 	Config bridge.CommandConfig
 	Output bridge.Output
@@ -77,7 +80,7 @@ func (p *Pool) worker() {
 		case <-p.ctx.Done():
 			p.results <- Result{
 				Config: config,
-				Err:    p.ctx.Err(),
+				Err:    fmt.Errorf("%w: %w", bridge.ErrCanceled, p.ctx.Err()),
 			}
 		default:
 			output, err := p.executor(p.ctx, config)
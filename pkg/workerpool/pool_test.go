@@ -2,6 +2,7 @@ package workerpool
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"sync/atomic"
 	"testing"
@@ -166,3 +167,21 @@ func TestPoolCancel(t *testing.T) {
 		}
 	}
 }
+
+func TestPoolCancelReportsErrCanceled(t *testing.T) {
+	executor, count := mockExecutor(time.Millisecond)
+	pool := NewPool(1, executor)
+
+	pool.Cancel()
+	pool.Submit(bridge.CommandConfig{Command: "never.exe"})
+	go pool.Shutdown()
+
+	for r := range pool.Results() {
+		if !errors.Is(r.Err, bridge.ErrCanceled) || !errors.Is(r.Err, context.Canceled) {
+			t.Errorf("Err = %v, want ErrCanceled wrapping context.Canceled", r.Err)
+		}
+	}
+	if n := count.Load(); n != 0 {
+		t.Errorf("executor ran %d times after Cancel, want 0", n)
+	}
+}
@@ -0,0 +1,177 @@
+package wslpath
+
+import (
+	"container/list"
+	"sync"
+	"time"
+)
+
+// DefaultPathCacheSize is the number of translations cached by default.
+const DefaultPathCacheSize = 4096
+
+// CacheStats reports the activity of the path translation cache.
+type CacheStats struct {
+	// Hits and Misses count lookups that found, or did not find, a
+	// cached translation.
+	Hits   uint64
+	Misses uint64
+
+	// Evictions counts translations dropped to stay within Capacity.
+	Evictions uint64
+
+	// Entries is the number of translations currently cached.
+	Entries int
+
+	// Capacity is the maximum number of cached translations.
+	Capacity int
+
+	// MountReloads counts mount table reloads that found a change and
+	// so invalidated the cache.
+	MountReloads uint64
+}
+
+// lruCache is a size-bounded map from cache keys to translations that
+// evicts the least recently used entry when full. Every translation
+// depends on the mount table, so the cache has a generation that a
+// reload advances; results computed under an older generation are not
+// stored.
+type lruCache struct {
+	mu       sync.Mutex
+	capacity int
+	order    *list.List // front is most recently used
+	items    map[string]*list.Element
+	gen      uint64
+
+	hits, misses, evictions, reloads uint64
+}
+
+type lruEntry struct {
+	key, value string
+}
+
+func newLRUCache(capacity int) *lruCache {
+	return &lruCache{
+		capacity: capacity,
+		order:    list.New(),
+		items:    make(map[string]*list.Element),
+	}
+}
+
+// generation returns the current generation, to pass to put.
+func (c *lruCache) generation() uint64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.gen
+}
+
+func (c *lruCache) get(key string) (string, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	el, ok := c.items[key]
+	if !ok {
+		c.misses++
+		return "", false
+	}
+	c.hits++
+	c.order.MoveToFront(el)
+	return el.Value.(*lruEntry).value, true
+}
+
+// put caches value under key, unless the cache has been invalidated
+// since generation gen.
+func (c *lruCache) put(key, value string, gen uint64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if gen != c.gen || c.capacity <= 0 {
+		return
+	}
+	if el, ok := c.items[key]; ok {
+		el.Value.(*lruEntry).value = value
+		c.order.MoveToFront(el)
+		return
+	}
+	c.items[key] = c.order.PushFront(&lruEntry{key: key, value: value})
+	c.evictLocked()
+}
+
+// evictLocked drops least recently used entries beyond capacity.
+func (c *lruCache) evictLocked() {
+	for c.order.Len() > max(c.capacity, 0) {
+		el := c.order.Back()
+		c.order.Remove(el)
+		delete(c.items, el.Value.(*lruEntry).key)
+		c.evictions++
+	}
+}
+
+// invalidate drops all entries and advances the generation.
+func (c *lruCache) invalidate() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.invalidateLocked()
+}
+
+// reload invalidates the cache after the mount table changed.
+func (c *lruCache) reload() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.reloads++
+	c.invalidateLocked()
+}
+
+func (c *lruCache) invalidateLocked() {
+	c.gen++
+	c.order.Init()
+	c.items = make(map[string]*list.Element)
+}
+
+func (c *lruCache) resize(capacity int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.capacity = capacity
+	c.evictLocked()
+}
+
+func (c *lruCache) stats() CacheStats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return CacheStats{
+		Hits:         c.hits,
+		Misses:       c.misses,
+		Evictions:    c.evictions,
+		Entries:      c.order.Len(),
+		Capacity:     c.capacity,
+		MountReloads: c.reloads,
+	}
+}
+
+// SetPathCacheSize sets how many translations the Default Resolver
+// caches, evicting the least recently used ones if there are more. A size
+// of zero or less disables caching.
+func SetPathCacheSize(n int) {
+	std.cache.resize(n)
+}
+
+// PathCacheStats returns the counters of the Default Resolver's cache.
+func PathCacheStats() CacheStats {
+	return std.CacheStats()
+}
+
+// SetMountCheckInterval makes the Default Resolver recheck /etc/wsl.conf
+// and /proc/mounts at most once per d, so drives mounted later, such as
+// with "mount -t drvfs E: /mnt/e", are picked up. Zero, the default, reads
+// them once; use ReloadMounts to recheck on demand.
+func SetMountCheckInterval(d time.Duration) {
+	std.mu.Lock()
+	defer std.mu.Unlock()
+	std.interval = d
+}
+
+// ReloadMounts makes the Default Resolver reread /etc/wsl.conf and
+// /proc/mounts. If the mount table changed, every cached translation is
+// dropped, since each depends on it.
+func ReloadMounts() {
+	std.mu.Lock()
+	defer std.mu.Unlock()
+	std.reloadLocked()
+}
@@ -0,0 +1,99 @@
+package wslpath
+
+import (
+	"testing"
+	"time"
+)
+
+func TestLRUCache(t *testing.T) {
+	c := newLRUCache(2)
+	gen := c.generation()
+	c.put("a", "1", gen)
+	c.put("b", "2", gen)
+	if v, ok := c.get("a"); !ok || v != "1" {
+		t.Fatalf("get(a) = %q, %v", v, ok)
+	}
+	c.put("c", "3", gen) // evicts b, the least recently used
+
+	if _, ok := c.get("b"); ok {
+		t.Error("b still cached after eviction")
+	}
+	for _, k := range []string{"a", "c"} {
+		if _, ok := c.get(k); !ok {
+			t.Errorf("%s evicted", k)
+		}
+	}
+	want := CacheStats{Hits: 3, Misses: 1, Evictions: 1, Entries: 2, Capacity: 2}
+	if got := c.stats(); got != want {
+		t.Errorf("stats = %+v, want %+v", got, want)
+	}
+
+	// A result computed before an invalidation is not stored.
+	c.invalidate()
+	c.put("d", "4", gen)
+	if _, ok := c.get("d"); ok {
+		t.Error("stale put was stored")
+	}
+
+	c.resize(0)
+	c.put("e", "5", c.generation())
+	if s := c.stats(); s.Entries != 0 || s.Capacity != 0 {
+		t.Errorf("disabled cache stats = %+v", s)
+	}
+}
+
+func TestReloadMounts(t *testing.T) {
+	setupMockMounts(t)
+
+	if got, _ := ToWindowsPath("/mnt/e/data"); got != `\\wsl.localhost\Ubuntu\mnt\e\data` {
+		t.Fatalf("before mount: got %q", got)
+	}
+	mountTableReader = func() (string, error) {
+		return mockMounts + "\nE:\\ /mnt/e 9p rw,aname=drvfs;path=E:\\ 0 0", nil
+	}
+	if got, _ := ToWindowsPath("/mnt/e/data"); got != `\\wsl.localhost\Ubuntu\mnt\e\data` {
+		t.Errorf("before reload: got %q, want the cached UNC path", got)
+	}
+
+	before := PathCacheStats().MountReloads
+	ReloadMounts()
+	if got, _ := ToWindowsPath("/mnt/e/data"); got != `E:\data` {
+		t.Errorf("after reload: got %q, want E:\\data", got)
+	}
+	ReloadMounts() // unchanged: the cache is kept
+	if s := PathCacheStats(); s.MountReloads != before+1 || s.Entries == 0 {
+		t.Errorf("stats after reloads = %+v, want one reload and entries kept", s)
+	}
+}
+
+func TestMountCheckInterval(t *testing.T) {
+	setupMockMounts(t)
+	SetMountCheckInterval(time.Nanosecond)
+
+	if got, _ := ToLinuxPath(`E:\data`); got != "/mnt/e/data" {
+		t.Fatalf("before mount: got %q", got)
+	}
+	mountTableReader = func() (string, error) {
+		return "E:\\ /drives/e 9p rw,aname=drvfs;path=E:\\ 0 0", nil
+	}
+	time.Sleep(time.Millisecond)
+	if got, _ := ToLinuxPath(`E:\other`); got != "/drives/e/other" {
+		t.Errorf("after mount: got %q, want /drives/e/other", got)
+	}
+	if got, _ := ToLinuxPath(`E:\data`); got != "/drives/e/data" {
+		t.Errorf("cached entry not invalidated: got %q", got)
+	}
+}
+
+func TestSetPathCacheSize(t *testing.T) {
+	setupMockMounts(t)
+	t.Cleanup(func() { SetPathCacheSize(DefaultPathCacheSize) })
+
+	SetPathCacheSize(2)
+	for _, p := range []string{"/a", "/b", "/c"} {
+		ToWindowsPath(p)
+	}
+	if s := PathCacheStats(); s.Entries != 2 || s.Capacity != 2 {
+		t.Errorf("stats = %+v, want 2 entries", s)
+	}
+}
@@ -0,0 +1,106 @@
+package wslpath
+
+import (
+	"bufio"
+	"os"
+	"strings"
+)
+
+// defaultAutomountRoot is where WSL mounts Windows drives unless
+// /etc/wsl.conf says otherwise.
+const defaultAutomountRoot = "/mnt/"
+
+// automountConfig holds the [automount] settings of /etc/wsl.conf.
+type automountConfig struct {
+	// Enabled reports whether fixed drives are mounted automatically.
+	Enabled bool
+	// Root is the directory drives are mounted under, with a trailing
+	// slash (e.g., "/mnt/", "/win/" or "/").
+	Root string
+	// Options are the DrvFs mount options, such as "metadata" or
+	// "case=dir", keyed by name. Flags map to "".
+	Options map[string]string
+}
+
+// wslConfReader reads /etc/wsl.conf. Replaceable for testing.
+var wslConfReader = defaultWSLConfReader
+
+func defaultWSLConfReader() (string, error) {
+	data, err := os.ReadFile("/etc/wsl.conf")
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
+
+// parseWSLConf extracts the [automount] settings from wsl.conf content.
+// Missing settings keep their WSL defaults.
+func parseWSLConf(content string) automountConfig {
+	conf := automountConfig{Enabled: true, Root: defaultAutomountRoot}
+	section := ""
+	scanner := bufio.NewScanner(strings.NewReader(content))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || line[0] == '#' || line[0] == ';' {
+			continue
+		}
+		if strings.HasPrefix(line, "[") && strings.HasSuffix(line, "]") {
+			section = strings.ToLower(strings.TrimSpace(line[1 : len(line)-1]))
+			continue
+		}
+		if section != "automount" {
+			continue
+		}
+		key, value, ok := strings.Cut(line, "=")
+		if !ok {
+			continue
+		}
+		key = strings.ToLower(strings.TrimSpace(key))
+		value = confValue(value)
+
+		switch key {
+		case "enabled":
+			conf.Enabled = !strings.EqualFold(value, "false")
+		case "root":
+			if value == "" {
+				continue
+			}
+			if !strings.HasSuffix(value, "/") {
+				value += "/"
+			}
+			conf.Root = value
+		case "options":
+			conf.Options = parseMountOptions(value)
+		}
+	}
+	return conf
+}
+
+// confValue strips an inline comment and surrounding quotes from a
+// wsl.conf value.
+func confValue(value string) string {
+	value = strings.TrimSpace(value)
+	if len(value) >= 2 && value[0] == '"' {
+		if end := strings.IndexByte(value[1:], '"'); end >= 0 {
+			return value[1 : end+1]
+		}
+	}
+	if i := strings.IndexAny(value, "#;"); i >= 0 {
+		value = strings.TrimSpace(value[:i])
+	}
+	return value
+}
+
+// parseMountOptions parses a mount option list such as
+// "rw,noatime,aname=drvfs;path=C:\;uid=1000". Both ',' and the ';' used
+// inside 9p options separate entries.
+func parseMountOptions(s string) map[string]string {
+	opts := make(map[string]string)
+	for _, opt := range strings.FieldsFunc(s, func(r rune) bool { return r == ',' || r == ';' }) {
+		key, value, _ := strings.Cut(strings.TrimSpace(opt), "=")
+		if key != "" {
+			opts[key] = value
+		}
+	}
+	return opts
+}
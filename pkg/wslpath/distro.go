@@ -0,0 +1,212 @@
+package wslpath
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ErrUnknownDistro is returned when a Linux path outside the drive mounts
+// needs a UNC path but the name of the WSL distro cannot be determined.
+// Use errors.Is to test for it.
+var ErrUnknownDistro = errors.New("wslpath: WSL distro name unknown")
+
+// localhostMinBuild is the first Windows build that serves WSL files under
+// \\wsl.localhost as well as \\wsl$.
+const localhostMinBuild = 21354
+
+// probeTimeout bounds the wslpath and cmd.exe runs made for discovery.
+const probeTimeout = 5 * time.Second
+
+var (
+	// procRoot is where process information is read. Replaceable for
+	// testing.
+	procRoot = "/proc"
+
+	// wslpathRunner returns the output of "wslpath -w /". Replaceable for
+	// testing.
+	wslpathRunner = defaultWSLPathRunner
+
+	// windowsVersionRunner returns the output of "cmd.exe /c ver".
+	// Replaceable for testing.
+	windowsVersionRunner = defaultWindowsVersionRunner
+
+	// discovered caches the result of discoverDistro.
+	discoveredOnce   sync.Once
+	discoveredDistro string
+	discoveredErr    error
+
+	// detectedHost caches the result of DetectHost.
+	detectedHostOnce sync.Once
+	detectedHost     string
+)
+
+// DistroName returns the name of the WSL distro this process runs in. It
+// is taken from WSL_DISTRO_NAME, which WSL sets for login shells; failing
+// that, from the environment of the nearest ancestor process that has it,
+// which covers sudo; failing that, from the UNC path "wslpath -w /"
+// prints, which also works under cron and systemd services. If all fail,
+// the error matches ErrUnknownDistro.
+//
+// Only the WSL_DISTRO_NAME lookup is repeated on every call; the result of
+// the other sources is cached.
+func DistroName() (string, error) {
+	if name := os.Getenv("WSL_DISTRO_NAME"); name != "" {
+		return name, nil
+	}
+	discoveredOnce.Do(func() {
+		discoveredDistro, discoveredErr = discoverDistro()
+	})
+	return discoveredDistro, discoveredErr
+}
+
+// discoverDistro finds the distro name without WSL_DISTRO_NAME.
+func discoverDistro() (string, error) {
+	if name := ancestorEnv("WSL_DISTRO_NAME"); name != "" {
+		return name, nil
+	}
+	out, err := wslpathRunner()
+	if err != nil {
+		return "", fmt.Errorf("%w: WSL_DISTRO_NAME is not set and wslpath failed: %v", ErrUnknownDistro, err)
+	}
+	name := distroFromUNC(strings.TrimSpace(out))
+	if name == "" {
+		return "", fmt.Errorf("%w: WSL_DISTRO_NAME is not set and wslpath printed %q", ErrUnknownDistro, strings.TrimSpace(out))
+	}
+	return name, nil
+}
+
+// distroFromUNC returns the distro of a \\wsl.localhost\<distro>\ or
+// \\wsl$\<distro>\ path, or "".
+func distroFromUNC(p string) string {
+	rest, ok := strings.CutPrefix(p, `\\`)
+	if !ok {
+		return ""
+	}
+	host, rest, _ := strings.Cut(rest, `\`)
+	if !strings.EqualFold(host, HostLocalhost) && !strings.EqualFold(host, HostLegacy) {
+		return ""
+	}
+	name, _, _ := strings.Cut(rest, `\`)
+	return name
+}
+
+// ancestorEnv returns the value of the environment variable key in the
+// nearest ancestor process that has it set, or "". Processes whose
+// environment cannot be read are skipped.
+func ancestorEnv(key string) string {
+	pid := parentPID(os.Getpid())
+	for depth := 0; pid > 1 && depth < 64; depth++ {
+		if data, err := os.ReadFile(filepath.Join(procRoot, strconv.Itoa(pid), "environ")); err == nil {
+			for _, kv := range strings.Split(string(data), "\x00") {
+				if v, ok := strings.CutPrefix(kv, key+"="); ok && v != "" {
+					return v
+				}
+			}
+		}
+		pid = parentPID(pid)
+	}
+	return ""
+}
+
+// parentPID returns the parent of process pid, or 0 if it is unknown.
+func parentPID(pid int) int {
+	f, err := os.Open(filepath.Join(procRoot, strconv.Itoa(pid), "status"))
+	if err != nil {
+		return 0
+	}
+	defer f.Close()
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		if v, ok := strings.CutPrefix(scanner.Text(), "PPid:"); ok {
+			ppid, _ := strconv.Atoi(strings.TrimSpace(v))
+			return ppid
+		}
+	}
+	return 0
+}
+
+func defaultWSLPathRunner() (string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), probeTimeout)
+	defer cancel()
+	out, err := exec.CommandContext(ctx, "wslpath", "-w", "/").Output()
+	return string(out), err
+}
+
+// DetectHost returns the UNC host this Windows build serves WSL files
+// under: HostLocalhost from build 21354 on, HostLegacy before it. If the
+// build cannot be determined, as when interop is disabled, it returns
+// HostLegacy, which every build supports. The build is read once, by
+// running "cmd.exe /c ver".
+func DetectHost() string {
+	detectedHostOnce.Do(func() {
+		detectedHost = HostLegacy
+		if build, err := windowsBuild(); err == nil && build >= localhostMinBuild {
+			detectedHost = HostLocalhost
+		}
+	})
+	return detectedHost
+}
+
+// windowsVersion matches the version in the output of "ver", such as
+// "Microsoft Windows [Version 10.0.22631.4037]".
+var windowsVersion = regexp.MustCompile(`(\d+)\.(\d+)\.(\d+)`)
+
+// windowsBuild returns the build number of Windows.
+func windowsBuild() (int, error) {
+	out, err := windowsVersionRunner()
+	if err != nil {
+		return 0, fmt.Errorf("failed to read the Windows version: %w", err)
+	}
+	m := windowsVersion.FindStringSubmatch(out)
+	if m == nil {
+		return 0, fmt.Errorf("unrecognized Windows version %q", strings.TrimSpace(out))
+	}
+	return strconv.Atoi(m[3])
+}
+
+func defaultWindowsVersionRunner() (string, error) {
+	t, _ := std.current()
+	cDrive, driveErr := t.drivePath("C")
+	cmdExe, err := exec.LookPath("cmd.exe")
+	if err != nil {
+		if driveErr != nil {
+			return "", driveErr
+		}
+		// Under sudo and cron, PATH lacks the Windows directories.
+		cmdExe = cDrive + "/Windows/System32/cmd.exe"
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), probeTimeout)
+	defer cancel()
+	cmd := exec.CommandContext(ctx, cmdExe, "/d", "/c", "ver")
+	// Start in a drive directory so cmd.exe does not warn about UNC paths.
+	if _, err := os.Stat(cDrive); driveErr == nil && err == nil {
+		cmd.Dir = cDrive
+	}
+	out, err := cmd.Output()
+	return string(out), err
+}
+
+// SetUNCHost sets the UNC host the Default Resolver uses, HostLocalhost or
+// HostLegacy, instead of the one DetectHost picks. An empty host restores
+// detection.
+func SetUNCHost(host string) {
+	std.mu.Lock()
+	defer std.mu.Unlock()
+	std.host = host
+	if std.t != nil {
+		t := *std.t
+		t.host = host
+		std.t = &t
+	}
+	std.cache.invalidate()
+}
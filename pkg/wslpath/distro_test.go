@@ -0,0 +1,166 @@
+package wslpath
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// stubDiscovery replaces the probes DistroName and DetectHost use: no
+// ancestor has WSL_DISTRO_NAME, wslpath fails, and "ver" reports version.
+func stubDiscovery(t *testing.T, version string) {
+	t.Helper()
+	procRoot = t.TempDir()
+	wslpathRunner = func() (string, error) { return "", errors.New("wslpath: not found") }
+	windowsVersionRunner = func() (string, error) {
+		return "\r\nMicrosoft Windows [Version " + version + "]\r\n", nil
+	}
+	resetMountTable()
+	t.Cleanup(func() {
+		procRoot = "/proc"
+		wslpathRunner = defaultWSLPathRunner
+		windowsVersionRunner = defaultWindowsVersionRunner
+		resetMountTable()
+	})
+}
+
+// writeProc creates the status and environ files of a fake process.
+func writeProc(t *testing.T, pid, ppid int, environ string) {
+	t.Helper()
+	dir := filepath.Join(procRoot, strconv.Itoa(pid))
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	status := "Name:\tsh\nPPid:\t" + strconv.Itoa(ppid) + "\n"
+	if err := os.WriteFile(filepath.Join(dir, "status"), []byte(status), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "environ"), []byte(environ), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestDistroName(t *testing.T) {
+	t.Run("env", func(t *testing.T) {
+		stubDiscovery(t, "10.0.22631")
+		t.Setenv("WSL_DISTRO_NAME", "Debian")
+		if got, err := DistroName(); err != nil || got != "Debian" {
+			t.Errorf("DistroName() = %q, %v; want Debian", got, err)
+		}
+	})
+
+	t.Run("ancestor", func(t *testing.T) {
+		stubDiscovery(t, "10.0.22631")
+		t.Setenv("WSL_DISTRO_NAME", "")
+		// sudo (self's parent) has a scrubbed environment; the shell above
+		// it does not.
+		writeProc(t, os.Getpid(), 500, "PATH=/usr/bin\x00")
+		writeProc(t, 500, 400, "SUDO_USER=me\x00")
+		writeProc(t, 400, 1, "HOME=/home/me\x00WSL_DISTRO_NAME=Ubuntu-22.04\x00")
+		if got, err := DistroName(); err != nil || got != "Ubuntu-22.04" {
+			t.Errorf("DistroName() = %q, %v; want Ubuntu-22.04", got, err)
+		}
+	})
+
+	t.Run("wslpath", func(t *testing.T) {
+		stubDiscovery(t, "10.0.22631")
+		t.Setenv("WSL_DISTRO_NAME", "")
+		wslpathRunner = func() (string, error) { return `\\wsl.localhost\kali-linux\` + "\n", nil }
+		if got, err := DistroName(); err != nil || got != "kali-linux" {
+			t.Errorf("DistroName() = %q, %v; want kali-linux", got, err)
+		}
+	})
+
+	t.Run("unknown", func(t *testing.T) {
+		stubDiscovery(t, "10.0.22631")
+		t.Setenv("WSL_DISTRO_NAME", "")
+		if _, err := DistroName(); !errors.Is(err, ErrUnknownDistro) {
+			t.Fatalf("DistroName() error = %v, want ErrUnknownDistro", err)
+		}
+		if got, err := ToWindowsPath("/home/me"); !errors.Is(err, ErrUnknownDistro) {
+			t.Errorf("ToWindowsPath = %q, %v; want ErrUnknownDistro instead of a guess", got, err)
+		}
+		if _, err := LoadConfig(); !errors.Is(err, ErrUnknownDistro) {
+			t.Errorf("LoadConfig error = %v, want ErrUnknownDistro", err)
+		}
+	})
+}
+
+func TestDetectHost(t *testing.T) {
+	tests := []struct {
+		name   string
+		output string
+		err    error
+		want   string
+	}{
+		{"windows 11", "Microsoft Windows [Version 10.0.22631.4037]", nil, HostLocalhost},
+		{"first localhost build", "Microsoft Windows [Version 10.0.21354.1]", nil, HostLocalhost},
+		{"windows 10 21H2", "Microsoft Windows [Version 10.0.19044.1288]", nil, HostLegacy},
+		{"localized", "Microsoft Windows [Versión 10.0.22000.318]", nil, HostLocalhost},
+		{"interop disabled", "", errors.New("exec format error"), HostLegacy},
+		{"garbage", "hello", nil, HostLegacy},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stubDiscovery(t, "")
+			windowsVersionRunner = func() (string, error) { return tt.output, tt.err }
+			if got := DetectHost(); got != tt.want {
+				t.Errorf("DetectHost() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWindowsVersionRunner_UsesMountedCDrive(t *testing.T) {
+	if _, err := os.Stat("/bin/sh"); err != nil {
+		t.Skip("/bin/sh not available")
+	}
+	// C: is mounted under a custom root and cmd.exe is not on PATH, as
+	// under sudo; a stand-in cmd.exe reports where it was started.
+	drive := filepath.Join(t.TempDir(), "win", "c")
+	system32 := filepath.Join(drive, "Windows", "System32")
+	if err := os.MkdirAll(system32, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	script := "#!/bin/sh\necho \"$(pwd) Microsoft Windows [Version 10.0.22631.4037]\"\n"
+	if err := os.WriteFile(filepath.Join(system32, "cmd.exe"), []byte(script), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	setupMockMounts(t)
+	mountTableReader = func() (string, error) {
+		return `C:\ ` + drive + ` 9p rw,noatime,dirsync,aname=drvfs;path=C:\;uid=1000;gid=1000 0 0`, nil
+	}
+	resetMountTable()
+	t.Setenv("PATH", "")
+
+	out, err := defaultWindowsVersionRunner()
+	if err != nil {
+		t.Fatalf("defaultWindowsVersionRunner: %v", err)
+	}
+	if want := drive + " Microsoft Windows"; !strings.HasPrefix(out, want) {
+		t.Errorf("output = %q, want it to start with %q", out, want)
+	}
+}
+
+func TestUNCHostSelection(t *testing.T) {
+	setupMockMounts(t)
+	windowsVersionRunner = func() (string, error) { return "Microsoft Windows [Version 10.0.19045.3803]", nil }
+
+	if got, _ := ToWindowsPath("/home/me"); got != `\\wsl$\Ubuntu\home\me` {
+		t.Errorf("old build: got %q, want \\\\wsl$", got)
+	}
+	SetUNCHost(HostLocalhost)
+	if got, _ := ToWindowsPath("/home/me"); got != `\\wsl.localhost\Ubuntu\home\me` {
+		t.Errorf("after SetUNCHost: got %q", got)
+	}
+	SetUNCHost("")
+	if got, _ := ToWindowsPath("/home/me"); got != `\\wsl$\Ubuntu\home\me` {
+		t.Errorf("after SetUNCHost(\"\"): got %q", got)
+	}
+	if got, _ := ToLinuxPath(`\\wsl.localhost\Ubuntu\home\me`); got != "/home/me" {
+		t.Errorf("ToLinuxPath accepts either host: got %q", got)
+	}
+}
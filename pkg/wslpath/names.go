@@ -0,0 +1,144 @@
+package wslpath
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf16"
+)
+
+// escapeBase is the start of the private-use range drvfs maps characters
+// that Windows forbids in file names to: ':' is stored as U+F03A.
+const escapeBase = 0xF000
+
+// maxNameLength is the longest file name NTFS allows, in UTF-16 code units.
+const maxNameLength = 255
+
+// forbidden reports whether r may not appear in a Windows file name:
+// control characters and \ : * ? " < > |.
+func forbidden(r rune) bool {
+	return r > 0 && r < 0x20 || strings.ContainsRune(`\:*?"<>|`, r)
+}
+
+// EscapeName maps the characters of a Linux file name that Windows does
+// not allow to the private-use characters drvfs stores them as: the
+// characters \ : * ? " < > |, control characters, and a trailing dot or
+// space. Other names are returned unchanged.
+func EscapeName(name string) string {
+	trailing := len(strings.TrimRight(name, ". "))
+	if trailing == len(name) && strings.IndexFunc(name, forbidden) < 0 {
+		return name
+	}
+	var b strings.Builder
+	for i, r := range name {
+		if forbidden(r) || i >= trailing {
+			r += escapeBase
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// UnescapeName reverses EscapeName, mapping the private-use characters
+// drvfs uses back to the characters they stand for.
+func UnescapeName(name string) string {
+	if !strings.ContainsFunc(name, isEscaped) {
+		return name
+	}
+	var b strings.Builder
+	for _, r := range name {
+		if isEscaped(r) {
+			r -= escapeBase
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// isEscaped reports whether r is a private-use character that EscapeName
+// produces.
+func isEscaped(r rune) bool {
+	c := r - escapeBase
+	return c > 0 && c < 0x80 && (forbidden(c) || c == '.' || c == ' ')
+}
+
+// escapePath applies EscapeName to each component of a path with
+// separator sep.
+func escapePath(p, sep string) string {
+	parts := strings.Split(p, sep)
+	for i, part := range parts {
+		parts[i] = EscapeName(part)
+	}
+	return strings.Join(parts, sep)
+}
+
+// NameIssue describes a component of a Linux path whose name is not a
+// valid Windows file name.
+type NameIssue struct {
+	// Component is the name as it appears in the Linux path.
+	Component string
+
+	// Reason explains what is wrong with it.
+	Reason string
+
+	// Escaped is true if drvfs stores the name with private-use
+	// characters instead (see EscapeName). Windows can then open the
+	// file, but programs show the name differently. If false, the name
+	// cannot be represented on Windows at all.
+	Escaped bool
+}
+
+// String returns a one-line description of the issue.
+func (n NameIssue) String() string {
+	return fmt.Sprintf("%q: %s", n.Component, n.Reason)
+}
+
+// reservedNames are the DOS device names Windows refuses as file names,
+// with or without an extension.
+var reservedNames = map[string]bool{
+	"CON": true, "PRN": true, "AUX": true, "NUL": true,
+	"COM1": true, "COM2": true, "COM3": true, "COM4": true, "COM5": true,
+	"COM6": true, "COM7": true, "COM8": true, "COM9": true,
+	"LPT1": true, "LPT2": true, "LPT3": true, "LPT4": true, "LPT5": true,
+	"LPT6": true, "LPT7": true, "LPT8": true, "LPT9": true,
+}
+
+// ValidateWindowsPath reports the components of linuxPath whose names
+// are not valid on Windows: names with forbidden characters or a trailing
+// dot or space, which drvfs escapes; reserved device names such as CON or
+// nul.txt; and names longer than 255 UTF-16 code units. It returns nil if
+// every component is valid.
+func ValidateWindowsPath(linuxPath string) []NameIssue {
+	var issues []NameIssue
+	for _, name := range strings.Split(linuxPath, "/") {
+		if name == "" || name == "." || name == ".." {
+			continue
+		}
+		if i := strings.IndexFunc(name, forbidden); i >= 0 {
+			issues = append(issues, NameIssue{
+				Component: name,
+				Reason:    fmt.Sprintf("contains %q, which Windows does not allow", name[i:i+1]),
+				Escaped:   true,
+			})
+		} else if strings.TrimRight(name, ". ") != name {
+			issues = append(issues, NameIssue{
+				Component: name,
+				Reason:    "ends with a dot or space",
+				Escaped:   true,
+			})
+		}
+		base, _, _ := strings.Cut(name, ".")
+		if reservedNames[strings.ToUpper(strings.TrimRight(base, " "))] {
+			issues = append(issues, NameIssue{
+				Component: name,
+				Reason:    "is a reserved device name",
+			})
+		}
+		if len(utf16.Encode([]rune(EscapeName(name)))) > maxNameLength {
+			issues = append(issues, NameIssue{
+				Component: name,
+				Reason:    fmt.Sprintf("is longer than %d characters", maxNameLength),
+			})
+		}
+	}
+	return issues
+}
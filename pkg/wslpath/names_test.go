@@ -0,0 +1,81 @@
+package wslpath
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestEscapeName(t *testing.T) {
+	tests := []struct {
+		name string
+		want string
+	}{
+		{"plain.txt", "plain.txt"},
+		{"a:b", "a\uf03ab"},
+		{`what?*"<>|\`, "what\uf03f\uf02a\uf022\uf03c\uf03e\uf07c\uf05c"},
+		{"trailing.", "trailing\uf02e"},
+		{"dots and space. .", "dots and space\uf02e\uf020\uf02e"},
+		{"tab\there", "tab\uf009here"},
+		{".hidden", ".hidden"},
+		{"ünïcode:✓", "ünïcode\uf03a✓"},
+	}
+	for _, tt := range tests {
+		got := EscapeName(tt.name)
+		if got != tt.want {
+			t.Errorf("EscapeName(%q) = %q, want %q", tt.name, got, tt.want)
+		}
+		if back := UnescapeName(got); back != tt.name {
+			t.Errorf("UnescapeName(%q) = %q, want %q", got, back, tt.name)
+		}
+	}
+
+	// Private-use characters outside the mapping are left alone.
+	if got := UnescapeName("\uf041\uf0ff"); got != "\uf041\uf0ff" {
+		t.Errorf("UnescapeName(unmapped) = %q", got)
+	}
+}
+
+func TestEscapedPathTranslation(t *testing.T) {
+	setupMockMounts(t)
+
+	win, err := ToWindowsPath("/mnt/c/logs/12:00/report?.txt")
+	if want := "C:\\logs\\12\uf03a00\\report\uf03f.txt"; err != nil || win != want {
+		t.Errorf("ToWindowsPath = %q, %v; want %q", win, err, want)
+	}
+	unc, _ := ToWindowsPath("/home/me/notes.")
+	if want := "\\\\wsl.localhost\\Ubuntu\\home\\me\\notes\uf02e"; unc != want {
+		t.Errorf("ToWindowsPath(UNC) = %q, want %q", unc, want)
+	}
+
+	for _, p := range []string{win, unc} {
+		back, err := ToLinuxPath(p)
+		if err != nil || strings.ContainsFunc(back, isEscaped) {
+			t.Errorf("ToLinuxPath(%q) = %q, %v; want unescaped", p, back, err)
+		}
+	}
+}
+
+func TestValidateWindowsPath(t *testing.T) {
+	if issues := ValidateWindowsPath("/mnt/c/Users/me/file.txt"); issues != nil {
+		t.Errorf("valid path: issues = %v", issues)
+	}
+
+	issues := ValidateWindowsPath("/data/a:b/ok/con.txt/trail./" + strings.Repeat("x", 256))
+	want := []NameIssue{
+		{Component: "a:b", Reason: `contains ":", which Windows does not allow`, Escaped: true},
+		{Component: "con.txt", Reason: "is a reserved device name"},
+		{Component: "trail.", Reason: "ends with a dot or space", Escaped: true},
+		{Component: strings.Repeat("x", 256), Reason: "is longer than 255 characters"},
+	}
+	if len(issues) != len(want) {
+		t.Fatalf("issues = %v, want %v", issues, want)
+	}
+	for i := range want {
+		if issues[i] != want[i] {
+			t.Errorf("issue %d = %+v, want %+v", i, issues[i], want[i])
+		}
+	}
+	if s := issues[1].String(); s != `"con.txt": is a reserved device name` {
+		t.Errorf("String() = %q", s)
+	}
+}
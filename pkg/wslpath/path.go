@@ -0,0 +1,313 @@
+// Package wslpath translates paths between Linux and Windows for programs
+// running under the Windows Subsystem for Linux, in pure Go: drive and
+// network share mounts are read from /proc/mounts rather than asked of the
+// wslpath tool.
+package wslpath
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"unicode"
+	"unicode/utf16"
+)
+
+// Mount is a drive or network share mounted through drvfs.
+type Mount struct {
+	// DriveLetter is the Windows drive letter (e.g., "C"), for a drive.
+	DriveLetter string
+	// Share is the UNC path of a network share (e.g., `\\fileserver\builds`),
+	// for a mounted share. Exactly one of DriveLetter and Share is set.
+	Share string
+	// MountPoint is the Linux mount path (e.g., "/mnt/c").
+	MountPoint string
+	// Options are the mount options, keyed by name.
+	Options map[string]string
+}
+
+var (
+	// std is the Resolver behind the package-level functions.
+	std = newSystemResolver()
+)
+
+// mountTableReader reads mount information. Replaceable for testing.
+var mountTableReader = defaultMountTableReader
+
+func defaultMountTableReader() (string, error) {
+	data, err := os.ReadFile("/proc/mounts")
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
+
+// parseMountTable extracts DrvFs mounts from /proc/mounts content.
+// Lines look like: "C:\ /mnt/c 9p ...;path=C:\;..." or "drvfs /mnt/c 9p ...".
+// A mount counts if its source or path= option is a drive letter or a UNC
+// share, at any depth, or, failing that, if it is a single letter under
+// the automount root. Entries are ordered longest mount point first.
+func parseMountTable(content string, conf automountConfig) []Mount {
+	var entries []Mount
+	scanner := bufio.NewScanner(strings.NewReader(content))
+	for scanner.Scan() {
+		line := scanner.Text()
+		fields := strings.Fields(line)
+		if len(fields) < 3 {
+			continue
+		}
+
+		source := unescapeMountField(fields[0])
+		mountPoint := unescapeMountField(fields[1])
+		fsType := fields[2]
+		if fsType != "9p" && fsType != "drvfs" {
+			continue
+		}
+		var opts map[string]string
+		if len(fields) > 3 {
+			opts = parseMountOptions(fields[3])
+		} else {
+			opts = conf.Options
+		}
+
+		letter := driveLetter(source)
+		if letter == "" {
+			letter = driveLetter(opts["path"])
+		}
+		share := uncShare(source)
+		if share == "" {
+			share = uncShare(opts["path"])
+		}
+		if letter == "" && share != "" {
+			entries = append(entries, Mount{
+				Share:      share,
+				MountPoint: mountPoint,
+				Options:    opts,
+			})
+			continue
+		}
+		if letter == "" && strings.HasPrefix(mountPoint, conf.Root) {
+			// Must be a single letter (the drive letter).
+			suffix := strings.TrimPrefix(mountPoint, conf.Root)
+			if len(suffix) == 1 && unicode.IsLetter(rune(suffix[0])) {
+				letter = strings.ToUpper(suffix)
+			}
+		}
+		if letter == "" {
+			continue
+		}
+		entries = append(entries, Mount{
+			DriveLetter: letter,
+			MountPoint:  mountPoint,
+			Options:     opts,
+		})
+	}
+	sort.SliceStable(entries, func(i, j int) bool {
+		return len(entries[i].MountPoint) > len(entries[j].MountPoint)
+	})
+	return entries
+}
+
+// driveLetter returns the uppercase drive letter of a mount source such as
+// "C:\" or "C:", or "" if s is not one.
+func driveLetter(s string) string {
+	s = strings.TrimSuffix(s, `\`)
+	if len(s) == 2 && s[1] == ':' && unicode.IsLetter(rune(s[0])) {
+		return strings.ToUpper(s[:1])
+	}
+	return ""
+}
+
+// uncShare returns the UNC path of a network share mount source such as
+// `\\fileserver\builds` or `UNC\fileserver\builds`, without a trailing
+// backslash, or "" if s is not one.
+func uncShare(s string) string {
+	if rest, ok := strings.CutPrefix(s, `UNC\`); ok {
+		s = `\\` + rest
+	}
+	if !strings.HasPrefix(s, `\\`) {
+		return ""
+	}
+	s = strings.TrimRight(s, `\`)
+	host, share, ok := strings.Cut(s[2:], `\`)
+	if !ok || host == "" || share == "" {
+		return ""
+	}
+	return s
+}
+
+// unescapeMountField decodes the octal escapes (\040 for a space) that
+// /proc/mounts uses in sources and mount points.
+func unescapeMountField(s string) string {
+	if !strings.Contains(s, `\`) {
+		return s
+	}
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] == '\\' && i+3 < len(s) && isOctal(s[i+1]) && isOctal(s[i+2]) && isOctal(s[i+3]) {
+			b.WriteByte((s[i+1]-'0')<<6 | (s[i+2]-'0')<<3 | (s[i+3] - '0'))
+			i += 3
+			continue
+		}
+		b.WriteByte(s[i])
+	}
+	return b.String()
+}
+
+func isOctal(c byte) bool {
+	return c >= '0' && c <= '7'
+}
+
+// Mode selects optional translation steps, which touch the filesystem.
+// The zero Mode translates by string manipulation alone.
+type Mode uint8
+
+// Mode flags.
+const (
+	// ResolveSymlinks evaluates symlinks in a Linux path before it is
+	// translated, so a link into a drive mount, such as ~/proj pointing
+	// at /mnt/c/work/proj, yields a drive path rather than a UNC path.
+	// Of a path that does not exist, the longest existing parent is
+	// resolved.
+	ResolveSymlinks Mode = 1 << iota
+
+	// CanonicalCase spells each component of a Linux path below a drive
+	// or share mount as its directory entry does, matching case
+	// insensitively like Windows: C:\USERS\me becomes /mnt/c/Users/me.
+	// It applies to the result of ToLinuxPath and the argument of
+	// ToWindowsPath. Components that do not exist are kept as given.
+	CanonicalCase
+)
+
+// cacheKey creates a unique key for the path cache.
+func cacheKey(direction string, mode Mode, path string) string {
+	return direction + strconv.Itoa(int(mode)) + ":" + path
+}
+
+// ToWindowsPath translates a Linux path to a Windows path with the
+// Default Resolver. See Resolver.ToWindowsPath.
+func ToWindowsPath(linuxPath string) (string, error) {
+	return std.ToWindowsPath(linuxPath)
+}
+
+// ToWindowsPathMode is ToWindowsPath with the optional steps of mode.
+// Results are memoized per mode; call ClearPathCache after changing
+// symlinks or renaming directories.
+func ToWindowsPathMode(linuxPath string, mode Mode) (string, error) {
+	return std.ToWindowsPathMode(linuxPath, mode)
+}
+
+// ToLinuxPath translates a Windows path to a Linux path with the Default
+// Resolver. See Resolver.ToLinuxPath.
+func ToLinuxPath(windowsPath string) (string, error) {
+	return std.ToLinuxPath(windowsPath)
+}
+
+// ToLinuxPathMode is ToLinuxPath with the optional steps of mode; only
+// CanonicalCase applies. Results are memoized per mode.
+func ToLinuxPathMode(windowsPath string, mode Mode) (string, error) {
+	return std.ToLinuxPathMode(windowsPath, mode)
+}
+
+// normalizeWindowsPath rewrites the long-path (\\?\) and device (\\.\)
+// forms of a Windows path, and forward slashes after a drive letter, to
+// the plain form: \\?\C:\x and C:/x become C:\x, \\?\UNC\server\share
+// becomes \\server\share. Device paths other than drives, such as
+// \\.\pipe\x, have no Linux equivalent and are an error.
+func normalizeWindowsPath(p string) (string, error) {
+	if strings.HasPrefix(p, `\\?\`) || strings.HasPrefix(p, `\\.\`) {
+		rest := p[4:]
+		switch {
+		case len(rest) >= 4 && strings.EqualFold(rest[:4], `UNC\`):
+			return `\\` + rest[4:], nil
+		case len(rest) >= 2 && rest[1] == ':' && unicode.IsLetter(rune(rest[0])):
+			p = rest
+		default:
+			return "", fmt.Errorf("device path %q has no Linux equivalent", p)
+		}
+	}
+	if len(p) >= 2 && p[1] == ':' && unicode.IsLetter(rune(p[0])) {
+		p = strings.ReplaceAll(p, "/", `\`)
+	}
+	return p, nil
+}
+
+// MaxPath is the Windows MAX_PATH limit in UTF-16 code units, including
+// the terminating NUL.
+const MaxPath = 260
+
+// LongPath returns winPath with the \\?\ prefix if it is too long for
+// MAX_PATH: \\?\C:\... for drive paths and \\?\UNC\server\share\... for
+// UNC paths. Programs that support long paths can then open it. Shorter
+// paths, and paths that already have a prefix, are returned unchanged.
+func LongPath(winPath string) string {
+	if len(utf16.Encode([]rune(winPath))) < MaxPath ||
+		strings.HasPrefix(winPath, `\\?\`) || strings.HasPrefix(winPath, `\\.\`) {
+		return winPath
+	}
+	if strings.HasPrefix(winPath, `\\`) {
+		return `\\?\UNC\` + winPath[2:]
+	}
+	return `\\?\` + winPath
+}
+
+// hasPathPrefixFold reports whether the Windows path p is prefix or lies
+// below it, ignoring case.
+func hasPathPrefixFold(p, prefix string) bool {
+	if len(p) < len(prefix) || !strings.EqualFold(p[:len(prefix)], prefix) {
+		return false
+	}
+	return len(p) == len(prefix) || p[len(prefix)] == '\\'
+}
+
+// resolveSymlinks evaluates the symlinks in the absolute path p. If p does
+// not exist, its longest existing parent is resolved and the rest kept.
+func resolveSymlinks(p string) string {
+	rest := ""
+	for dir := p; ; dir = filepath.Dir(dir) {
+		if resolved, err := filepath.EvalSymlinks(dir); err == nil {
+			return filepath.Join(resolved, rest)
+		}
+		if dir == "/" || dir == "." {
+			return p
+		}
+		rest = filepath.Join(filepath.Base(dir), rest)
+	}
+}
+
+// matchEntry returns the name of the entry in dir that matches name,
+// exactly or else ignoring case. The entries are listed rather than
+// looked up, since a lookup on drvfs ignores case too.
+func matchEntry(dir, name string) (string, bool) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return "", false
+	}
+	match := ""
+	for _, e := range entries {
+		if e.Name() == name {
+			return name, true
+		}
+		if match == "" && strings.EqualFold(e.Name(), name) {
+			match = e.Name()
+		}
+	}
+	return match, match != ""
+}
+
+// ClearPathCache clears the memoized path cache of the Default Resolver.
+// The counters reported by PathCacheStats are kept.
+func ClearPathCache() {
+	std.ClearCache()
+}
+
+// resetMountTable resets mount table state for testing.
+func resetMountTable() {
+	std = newSystemResolver()
+	discoveredOnce = sync.Once{}
+	detectedHostOnce = sync.Once{}
+}
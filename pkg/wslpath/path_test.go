@@ -0,0 +1,508 @@
+package wslpath
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// mockMounts is realistic /proc/mounts content for testing.
+const mockMounts = `none / 9p rw,relatime,dirsync,aname=drvfs;path=\,symlinkroot=/mnt/wsl 0 0
+none /init 9p rw,relatime 0 0
+none /dev tmpfs rw,nosuid,relatime,mode=755 0 0
+C:\ /mnt/c 9p rw,noatime,dirsync,aname=drvfs;path=C:\;uid=1000;gid=1000;symlinkroot=/mnt/wsl 0 0
+D:\ /mnt/d 9p rw,noatime,dirsync,aname=drvfs;path=D:\;uid=1000;gid=1000;symlinkroot=/mnt/wsl 0 0
+none /run tmpfs rw,nosuid,noexec,relatime 0 0
+tmpfs /sys/fs/cgroup tmpfs rw,nosuid,nodev,noexec,relatime,mode=755 0 0`
+
+func setupMockMounts(t *testing.T) {
+	t.Helper()
+	resetMountTable()
+	ClearPathCache()
+	mountTableReader = func() (string, error) {
+		return mockMounts, nil
+	}
+	wslConfReader = func() (string, error) {
+		return "", os.ErrNotExist
+	}
+	stubDiscovery(t, "10.0.22631.4037")
+	os.Setenv("WSL_DISTRO_NAME", "Ubuntu")
+	t.Cleanup(func() {
+		mountTableReader = defaultMountTableReader
+		wslConfReader = defaultWSLConfReader
+		resetMountTable()
+		ClearPathCache()
+		os.Unsetenv("WSL_DISTRO_NAME")
+	})
+}
+
+func TestParseMountTable(t *testing.T) {
+	entries := parseMountTable(mockMounts, parseWSLConf(""))
+	if len(entries) != 2 {
+		t.Fatalf("expected 2 mount entries, got %d", len(entries))
+	}
+	if entries[0].DriveLetter != "C" || entries[0].MountPoint != "/mnt/c" {
+		t.Errorf("entry[0] = %+v, want C:/mnt/c", entries[0])
+	}
+	if entries[1].DriveLetter != "D" || entries[1].MountPoint != "/mnt/d" {
+		t.Errorf("entry[1] = %+v, want D:/mnt/d", entries[1])
+	}
+}
+
+func TestToWindowsPath(t *testing.T) {
+	setupMockMounts(t)
+
+	tests := []struct {
+		name    string
+		input   string
+		want    string
+		wantErr bool
+	}{
+		{
+			name:  "drive root",
+			input: "/mnt/c",
+			want:  `C:\`,
+		},
+		{
+			name:  "drive subdir",
+			input: "/mnt/c/Users/test/file.txt",
+			want:  `C:\Users\test\file.txt`,
+		},
+		{
+			name:  "D drive",
+			input: "/mnt/d/projects",
+			want:  `D:\projects`,
+		},
+		{
+			name:  "non-mount path (home)",
+			input: "/home/user/code",
+			want:  `\\wsl.localhost\Ubuntu\home\user\code`,
+		},
+		{
+			name:  "root path",
+			input: "/",
+			want:  `\\wsl.localhost\Ubuntu\`,
+		},
+		{
+			name:  "relative dot-dot resolved",
+			input: "/mnt/c/Users/../Users/test",
+			want:  `C:\Users\test`,
+		},
+		{
+			name:    "empty path",
+			input:   "",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ToWindowsPath(tt.input)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ToWindowsPath(%q) error = %v, wantErr %v", tt.input, err, tt.wantErr)
+				return
+			}
+			if got != tt.want {
+				t.Errorf("ToWindowsPath(%q) = %q, want %q", tt.input, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestToLinuxPath(t *testing.T) {
+	setupMockMounts(t)
+
+	tests := []struct {
+		name    string
+		input   string
+		want    string
+		wantErr bool
+	}{
+		{
+			name:  "C drive path",
+			input: `C:\Users\test`,
+			want:  "/mnt/c/Users/test",
+		},
+		{
+			name:  "C drive root",
+			input: `C:\`,
+			want:  "/mnt/c",
+		},
+		{
+			name:  "D drive",
+			input: `D:\projects\foo`,
+			want:  "/mnt/d/projects/foo",
+		},
+		{
+			name:  "UNC wsl.localhost",
+			input: `\\wsl.localhost\Ubuntu\home\user`,
+			want:  "/home/user",
+		},
+		{
+			name:  "UNC wsl$",
+			input: `\\wsl$\Ubuntu\tmp\file.txt`,
+			want:  "/tmp/file.txt",
+		},
+		{
+			name:    "empty path",
+			input:   "",
+			wantErr: true,
+		},
+		{
+			name:    "unrecognized format",
+			input:   "not-a-path",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ToLinuxPath(tt.input)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ToLinuxPath(%q) error = %v, wantErr %v", tt.input, err, tt.wantErr)
+				return
+			}
+			if got != tt.want {
+				t.Errorf("ToLinuxPath(%q) = %q, want %q", tt.input, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPathCaching(t *testing.T) {
+	setupMockMounts(t)
+
+	// First call computes.
+	r1, _ := ToWindowsPath("/mnt/c/test")
+	// Second call should return cached result.
+	r2, _ := ToWindowsPath("/mnt/c/test")
+
+	if r1 != r2 {
+		t.Errorf("cached result mismatch: %q vs %q", r1, r2)
+	}
+}
+
+func TestMountTableReadError(t *testing.T) {
+	resetMountTable()
+	ClearPathCache()
+	mountTableReader = func() (string, error) {
+		return "", os.ErrNotExist
+	}
+	wslConfReader = func() (string, error) {
+		return "", os.ErrNotExist
+	}
+	stubDiscovery(t, "10.0.22631.4037")
+	os.Setenv("WSL_DISTRO_NAME", "TestDistro")
+	t.Cleanup(func() {
+		mountTableReader = defaultMountTableReader
+		wslConfReader = defaultWSLConfReader
+		resetMountTable()
+		ClearPathCache()
+		os.Unsetenv("WSL_DISTRO_NAME")
+	})
+
+	// With no mount table, all paths become UNC.
+	got, err := ToWindowsPath("/mnt/c/test")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	// Falls through to UNC since no mounts parsed.
+	want := `\\wsl.localhost\TestDistro\mnt\c\test`
+	if got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+// customRootMounts is /proc/mounts content with "root = /" in wsl.conf,
+// plus a drive mounted by hand deeper in the tree.
+const customRootMounts = `none / ext4 rw,relatime 0 0
+C:\134 /c 9p rw,noatime,aname=drvfs;path=C:\;uid=1000;gid=1000;case=dir 0 0
+drvfs /e 9p rw,noatime 0 0
+none /data/win\040share 9p rw,aname=drvfs;path=D:\;uid=1000 0 0
+none /proc proc rw 0 0`
+
+func setupCustomRoot(t *testing.T, conf string) {
+	t.Helper()
+	setupMockMounts(t)
+	mountTableReader = func() (string, error) {
+		return customRootMounts, nil
+	}
+	wslConfReader = func() (string, error) {
+		return conf, nil
+	}
+}
+
+func TestParseMountTable_AnyDepth(t *testing.T) {
+	entries := parseMountTable(customRootMounts, parseWSLConf("[automount]\nroot = /\n"))
+	got := make(map[string]string)
+	for _, e := range entries {
+		got[e.DriveLetter] = e.MountPoint
+	}
+	want := map[string]string{"C": "/c", "D": "/data/win share", "E": "/e"}
+	if len(got) != len(want) {
+		t.Fatalf("entries = %+v, want %v", entries, want)
+	}
+	for letter, mp := range want {
+		if got[letter] != mp {
+			t.Errorf("drive %s mounted at %q, want %q", letter, got[letter], mp)
+		}
+	}
+	if entries[0].DriveLetter != "D" {
+		t.Errorf("entries[0] = %+v, want the longest mount point first", entries[0])
+	}
+	for _, e := range entries {
+		if e.DriveLetter == "C" && e.Options["case"] != "dir" {
+			t.Errorf("C: options = %v, want case=dir", e.Options)
+		}
+	}
+}
+
+func TestCustomAutomountRoot(t *testing.T) {
+	setupCustomRoot(t, "[automount]\nenabled = true\nroot = /\n")
+
+	toWin := map[string]string{
+		"/c/Users/me":           `C:\Users\me`,
+		"/c":                    `C:\`,
+		"/data/win share/x.txt": `D:\x.txt`,
+		"/mnt/c/Users":          `\\wsl.localhost\Ubuntu\mnt\c\Users`,
+	}
+	for in, want := range toWin {
+		if got, err := ToWindowsPath(in); err != nil || got != want {
+			t.Errorf("ToWindowsPath(%q) = %q, %v; want %q", in, got, err, want)
+		}
+	}
+
+	toLinux := map[string]string{
+		`C:\Users\me`: "/c/Users/me",
+		`c:\`:         "/c",
+		`D:\x.txt`:    "/data/win share/x.txt",
+		`F:\games`:    "/f/games", // not mounted: where automount would put it
+	}
+	for in, want := range toLinux {
+		if got, err := ToLinuxPath(in); err != nil || got != want {
+			t.Errorf("ToLinuxPath(%q) = %q, %v; want %q", in, got, err, want)
+		}
+	}
+}
+
+func TestAutomountDisabled(t *testing.T) {
+	setupCustomRoot(t, "[automount]\nenabled = false\nroot = /\n")
+
+	if got, err := ToLinuxPath(`C:\Users\me`); err != nil || got != "/c/Users/me" {
+		t.Errorf("ToLinuxPath(C:\\Users\\me) = %q, %v; want the mounted drive", got, err)
+	}
+	for _, in := range []string{`F:\games`, `f:`} {
+		if got, err := ToLinuxPath(in); err == nil || !strings.Contains(err.Error(), "drive F: is not mounted") {
+			t.Errorf("ToLinuxPath(%q) = %q, %v; want an unmounted drive error", in, got, err)
+		}
+	}
+}
+
+func TestToLinuxPath_NestedRoot(t *testing.T) {
+	setupMockMounts(t)
+	mountTableReader = func() (string, error) { return "", os.ErrNotExist }
+	wslConfReader = func() (string, error) { return "[automount]\nroot = /win\n", nil }
+
+	if got, _ := ToLinuxPath(`C:\x`); got != "/win/c/x" {
+		t.Errorf("ToLinuxPath(C:\\x) = %q, want /win/c/x", got)
+	}
+}
+
+func TestParseWSLConf(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    automountConfig
+	}{
+		{"empty", "", automountConfig{Enabled: true, Root: "/mnt/"}},
+		{
+			"custom",
+			"[boot]\nsystemd=true\n\n[automount]\n# comment\nenabled = false\nroot = /win  # drives here\noptions = \"metadata,umask=22\"\n",
+			automountConfig{Root: "/win/", Options: map[string]string{"metadata": "", "umask": "22"}},
+		},
+		{"root only in other section", "[network]\nroot = /x\n", automountConfig{Enabled: true, Root: "/mnt/"}},
+		{"section case", "[AutoMount]\nRoot=/\n", automountConfig{Enabled: true, Root: "/"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := parseWSLConf(tt.content)
+			if got.Enabled != tt.want.Enabled || got.Root != tt.want.Root || len(got.Options) != len(tt.want.Options) {
+				t.Fatalf("parseWSLConf = %+v, want %+v", got, tt.want)
+			}
+			for k, v := range tt.want.Options {
+				if got.Options[k] != v {
+					t.Errorf("option %s = %q, want %q", k, got.Options[k], v)
+				}
+			}
+		})
+	}
+}
+
+// shareMounts is /proc/mounts content with network shares mounted through
+// drvfs, one of them below another.
+const shareMounts = `C:\134 /mnt/c 9p rw,noatime,aname=drvfs;path=C:\;uid=1000 0 0
+\134\134fileserver\134builds /mnt/builds 9p rw,noatime,aname=drvfs;path=UNC\fileserver\builds;uid=1000 0 0
+drvfs /srv/nightly 9p rw,noatime,aname=drvfs;path=\\fileserver\builds\nightly\;uid=1000 0 0
+Z: /mnt/z 9p rw,noatime,aname=drvfs;path=Z:;uid=1000 0 0`
+
+func TestNetworkShares(t *testing.T) {
+	setupMockMounts(t)
+	mountTableReader = func() (string, error) {
+		return shareMounts, nil
+	}
+
+	toWin := map[string]string{
+		"/mnt/builds":               `\\fileserver\builds`,
+		"/mnt/builds/v1/app.zip":    `\\fileserver\builds\v1\app.zip`,
+		"/srv/nightly/2024-01-01":   `\\fileserver\builds\nightly\2024-01-01`,
+		"/mnt/z/mapped/report.docx": `Z:\mapped\report.docx`,
+	}
+	for in, want := range toWin {
+		if got, err := ToWindowsPath(in); err != nil || got != want {
+			t.Errorf("ToWindowsPath(%q) = %q, %v; want %q", in, got, err, want)
+		}
+	}
+
+	toLinux := map[string]string{
+		`\\fileserver\builds`:                 "/mnt/builds",
+		`\\FileServer\Builds\v1\app.zip`:      "/mnt/builds/v1/app.zip",
+		`\\fileserver\builds\nightly\x.log`:   "/srv/nightly/x.log",
+		`\\fileserver\builds\nightlyish\x`:    "/mnt/builds/nightlyish/x",
+		`Z:\mapped\report.docx`:               "/mnt/z/mapped/report.docx",
+		`\\wsl.localhost\Ubuntu\home\me\file`: "/home/me/file",
+	}
+	for in, want := range toLinux {
+		if got, err := ToLinuxPath(in); err != nil || got != want {
+			t.Errorf("ToLinuxPath(%q) = %q, %v; want %q", in, got, err, want)
+		}
+	}
+
+	_, err := ToLinuxPath(`\\otherserver\share\dir\file.txt`)
+	if err == nil || !strings.Contains(err.Error(), `network share \\otherserver\share is not mounted`) {
+		t.Errorf("unmapped share: err = %v", err)
+	}
+	if _, err := ToLinuxPath(`\\lonelyhost`); err == nil || !strings.Contains(err.Error(), "unrecognized") {
+		t.Errorf("bare host: err = %v", err)
+	}
+}
+
+func TestToLinuxPath_LongAndForwardSlash(t *testing.T) {
+	setupMockMounts(t)
+	mountTableReader = func() (string, error) {
+		return shareMounts, nil
+	}
+
+	tests := []struct {
+		input   string
+		want    string
+		wantErr string
+	}{
+		{input: `\\?\C:\very\long\path`, want: "/mnt/c/very/long/path"},
+		{input: `\\?\c:\`, want: "/mnt/c"},
+		{input: `\\?\UNC\fileserver\builds\v1`, want: "/mnt/builds/v1"},
+		{input: `\\?\unc\wsl.localhost\Ubuntu\home\me`, want: "/home/me"},
+		{input: `\\.\C:\Windows`, want: "/mnt/c/Windows"},
+		{input: `C:/Users/me/app.js`, want: "/mnt/c/Users/me/app.js"},
+		{input: `C:/`, want: "/mnt/c"},
+		{input: `c:\mixed/slashes\x`, want: "/mnt/c/mixed/slashes/x"},
+		{input: `\\.\pipe\docker_engine`, wantErr: "no Linux equivalent"},
+		{input: `\\?\Volume{1b3b1146-4076-11e1-84aa-806e6f6e6963}\x`, wantErr: "no Linux equivalent"},
+	}
+	for _, tt := range tests {
+		got, err := ToLinuxPath(tt.input)
+		if tt.wantErr != "" {
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("ToLinuxPath(%q) = %q, %v; want error %q", tt.input, got, err, tt.wantErr)
+			}
+			continue
+		}
+		if err != nil || got != tt.want {
+			t.Errorf("ToLinuxPath(%q) = %q, %v; want %q", tt.input, got, err, tt.want)
+		}
+	}
+}
+
+func TestLongPath(t *testing.T) {
+	long := `C:\` + strings.Repeat(`dir\`, 70) + "file.txt"
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{`C:\short`, `C:\short`},
+		{long, `\\?\` + long},
+		{`\\server\share\` + long[3:], `\\?\UNC\server\share\` + long[3:]},
+		{`\\?\` + long, `\\?\` + long},
+		{`C:\` + strings.Repeat("x", MaxPath-4), `C:\` + strings.Repeat("x", MaxPath-4)},
+		{`C:\` + strings.Repeat("x", MaxPath-3), `\\?\C:\` + strings.Repeat("x", MaxPath-3)},
+	}
+	for _, tt := range tests {
+		if got := LongPath(tt.input); got != tt.want {
+			t.Errorf("LongPath(%.20q...) = %.30q..., want %.30q...", tt.input, got, tt.want)
+		}
+	}
+}
+
+// setupDriveDir mounts a temporary directory as drive C: and returns the
+// directory that stands for the mount point.
+func setupDriveDir(t *testing.T) string {
+	t.Helper()
+	setupMockMounts(t)
+	drive := filepath.Join(t.TempDir(), "c")
+	if err := os.MkdirAll(filepath.Join(drive, "Users", "Me", "proj"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	mountTableReader = func() (string, error) {
+		return `C:\134 ` + drive + ` 9p rw,noatime,aname=drvfs;path=C:\;uid=1000 0 0`, nil
+	}
+	return drive
+}
+
+func TestToWindowsPathMode_ResolveSymlinks(t *testing.T) {
+	drive := setupDriveDir(t)
+	link := filepath.Join(t.TempDir(), "proj")
+	if err := os.Symlink(filepath.Join(drive, "Users", "Me", "proj"), link); err != nil {
+		t.Fatal(err)
+	}
+
+	plain, err := ToWindowsPath(link)
+	if err != nil || !strings.HasPrefix(plain, `\\wsl.localhost\`) {
+		t.Errorf("ToWindowsPath(link) = %q, %v; want a UNC path to the link itself", plain, err)
+	}
+	tests := map[string]string{
+		link:                                  `C:\Users\Me\proj`,
+		filepath.Join(link, "sub", "new.txt"): `C:\Users\Me\proj\sub\new.txt`,
+		filepath.Join(drive, "Users", "Me", ".."): `C:\Users`,
+	}
+	for in, want := range tests {
+		if got, err := ToWindowsPathMode(in, ResolveSymlinks); err != nil || got != want {
+			t.Errorf("ToWindowsPathMode(%q, ResolveSymlinks) = %q, %v; want %q", in, got, err, want)
+		}
+	}
+}
+
+func TestPathMode_CanonicalCase(t *testing.T) {
+	drive := setupDriveDir(t)
+
+	if got, _ := ToLinuxPath(`C:\USERS\me\PROJ\missing`); got != drive+"/USERS/me/PROJ/missing" {
+		t.Errorf("ToLinuxPath without CanonicalCase = %q, want the case kept", got)
+	}
+	got, err := ToLinuxPathMode(`C:\USERS\me\PROJ\missing`, CanonicalCase)
+	if want := drive + "/Users/Me/proj/missing"; err != nil || got != want {
+		t.Errorf("ToLinuxPathMode(CanonicalCase) = %q, %v; want %q", got, err, want)
+	}
+
+	got, err = ToWindowsPathMode(drive+"/users/ME", CanonicalCase)
+	if err != nil || got != `C:\Users\Me` {
+		t.Errorf("ToWindowsPathMode(CanonicalCase) = %q, %v; want C:\\Users\\Me", got, err)
+	}
+
+	// An exact match wins over a case-insensitive one.
+	if err := os.Mkdir(filepath.Join(drive, "users"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	ClearPathCache()
+	if got, _ := ToLinuxPathMode(`C:\users`, CanonicalCase); got != drive+"/users" {
+		t.Errorf("exact match: got %q", got)
+	}
+}
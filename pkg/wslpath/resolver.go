@@ -0,0 +1,469 @@
+package wslpath
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+)
+
+// UNC hosts under which Windows serves the files of WSL distros.
+const (
+	// HostLocalhost is the host of Windows 10 build 21354 and later:
+	// \\wsl.localhost\<distro>\.
+	HostLocalhost = "wsl.localhost"
+
+	// HostLegacy is the host every Windows build supports:
+	// \\wsl$\<distro>\.
+	HostLegacy = "wsl$"
+)
+
+// Config describes the WSL environment a Resolver translates paths for.
+type Config struct {
+	// Mounts are the drives and network shares mounted through drvfs.
+	// Their order does not matter: the longest matching mount point wins.
+	Mounts []Mount
+
+	// AutomountRoot is the directory drives missing from Mounts are
+	// assumed to be mounted under, as set by the [automount] root of
+	// /etc/wsl.conf. Empty means "/mnt/".
+	AutomountRoot string
+
+	// NoAutomount reports that drives are not mounted automatically, as
+	// with [automount] enabled=false in /etc/wsl.conf. Windows paths on
+	// drives missing from Mounts then cannot be translated.
+	NoAutomount bool
+
+	// Distro is the name of the WSL distro, used in the UNC paths of
+	// Linux paths outside Mounts. If empty, such paths cannot be
+	// translated to Windows.
+	Distro string
+
+	// Host is the UNC host of those paths, HostLocalhost or HostLegacy.
+	// Empty means HostLocalhost. Windows paths on either host are
+	// accepted regardless.
+	Host string
+
+	// CacheSize is the number of translations memoized. Zero means
+	// DefaultPathCacheSize; a negative size disables caching.
+	CacheSize int
+}
+
+// ParseConfig builds a Config from the content of /proc/mounts and
+// /etc/wsl.conf, either of which may be empty. Distro and Host are left
+// for the caller to set.
+func ParseConfig(procMounts, wslConf string) Config {
+	conf := parseWSLConf(wslConf)
+	return Config{
+		Mounts:        parseMountTable(procMounts, conf),
+		AutomountRoot: conf.Root,
+		NoAutomount:   !conf.Enabled,
+	}
+}
+
+// LoadConfig returns the Config of the running system: the mounts in
+// /proc/mounts, the automount settings of /etc/wsl.conf, the distro name from
+// DistroName and the UNC host from DetectHost. Files that cannot be read
+// are treated as empty. If the distro name cannot be determined, the
+// Config is returned with an empty Distro and the error of DistroName.
+func LoadConfig() (Config, error) {
+	cfg, _ := loadSystemConfig()
+	cfg.Host = DetectHost()
+	distro, err := DistroName()
+	cfg.Distro = distro
+	return cfg, err
+}
+
+// loadSystemConfig returns the mounts and automount settings of the running
+// system and the content they were parsed from.
+func loadSystemConfig() (Config, string) {
+	conf, err := wslConfReader()
+	if err != nil {
+		conf = ""
+	}
+	content, err := mountTableReader()
+	if err != nil {
+		content = ""
+	}
+	return ParseConfig(content, conf), conf + "\x00" + content
+}
+
+// A Resolver translates paths between Linux and Windows for one WSL
+// environment. Translations are memoized. A Resolver is safe for
+// concurrent use.
+type Resolver struct {
+	cache *lruCache
+
+	mu sync.Mutex
+	t  *table
+
+	// load rereads the mount table, for the Resolver returned by Default;
+	// it is nil for a Resolver with a fixed table. The remaining fields
+	// are only used with it.
+	load     func() (Config, string)
+	host     string // set by SetUNCHost
+	source   string // content the table was parsed from
+	checked  time.Time
+	interval time.Duration
+}
+
+// table is the state a translation depends on.
+type table struct {
+	mounts    []Mount // longest mount point first
+	root      string  // automount root, with a trailing slash
+	automount bool    // drives missing from mounts are under root
+	distro    string
+	host      string
+
+	// discover makes an empty distro and host be found with DistroName
+	// and DetectHost when a UNC path is needed.
+	discover bool
+}
+
+// New returns a Resolver for cfg. It does not read /proc/mounts or any
+// other file; only the optional steps of a Mode touch the filesystem.
+func New(cfg Config) *Resolver {
+	size := cfg.CacheSize
+	if size == 0 {
+		size = DefaultPathCacheSize
+	}
+	if cfg.Host == "" {
+		cfg.Host = HostLocalhost
+	}
+	return &Resolver{cache: newLRUCache(size), t: newTable(cfg)}
+}
+
+// newSystemResolver returns a Resolver that reads the Config of the
+// running system on first use.
+func newSystemResolver() *Resolver {
+	return &Resolver{cache: newLRUCache(DefaultPathCacheSize), load: loadSystemConfig}
+}
+
+func newTable(cfg Config) *table {
+	t := &table{
+		mounts:    append([]Mount(nil), cfg.Mounts...),
+		root:      cfg.AutomountRoot,
+		automount: !cfg.NoAutomount,
+		distro:    cfg.Distro,
+		host:      cfg.Host,
+	}
+	if t.root == "" {
+		t.root = defaultAutomountRoot
+	} else if !strings.HasSuffix(t.root, "/") {
+		t.root += "/"
+	}
+	sort.SliceStable(t.mounts, func(i, j int) bool {
+		return len(t.mounts[i].MountPoint) > len(t.mounts[j].MountPoint)
+	})
+	return t
+}
+
+// Default returns the Resolver behind the package-level functions, which
+// reads its Config from the running system; see LoadConfig.
+func Default() *Resolver {
+	return std
+}
+
+// current returns the table to translate with and the cache generation
+// that goes with it, reloading the table first if it is due.
+func (r *Resolver) current() (*table, uint64) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.load != nil && (r.t == nil || r.interval > 0 && time.Since(r.checked) >= r.interval) {
+		r.reloadLocked()
+	}
+	return r.t, r.cache.generation()
+}
+
+// reloadLocked rereads the table and invalidates the cache if it changed.
+// r.mu must be held.
+func (r *Resolver) reloadLocked() {
+	if r.load == nil {
+		return
+	}
+	cfg, source := r.load()
+	r.checked = time.Now()
+	if r.t != nil && source == r.source {
+		return
+	}
+	loaded := r.t != nil
+	cfg.Host = r.host
+	r.t = newTable(cfg)
+	r.t.discover = true
+	r.source = source
+	if loaded {
+		r.cache.reload()
+	}
+}
+
+// ToWindowsPath translates an absolute Linux path to a Windows path.
+// Relative paths are rejected, since the Windows side has no notion of
+// the Linux working directory.
+//
+// Algorithm:
+//  1. Check if path is under a known drive mount, such as /mnt/<letter> → "X:\rest\of\path"
+//  2. Otherwise, generate UNC path → "\\wsl.localhost\<distro>\path"
+//
+// Characters Windows does not allow in file names are escaped as drvfs
+// does; see EscapeName. Paths longer than MAX_PATH are returned without a
+// \\?\ prefix; see LongPath. Results are memoized.
+func (r *Resolver) ToWindowsPath(linuxPath string) (string, error) {
+	return r.ToWindowsPathMode(linuxPath, 0)
+}
+
+// ToWindowsPathMode is ToWindowsPath with the optional steps of mode.
+// Results are memoized per mode; call ClearCache after changing symlinks
+// or renaming directories.
+func (r *Resolver) ToWindowsPathMode(linuxPath string, mode Mode) (string, error) {
+	if linuxPath == "" {
+		return "", fmt.Errorf("empty path provided")
+	}
+	if !filepath.IsAbs(linuxPath) {
+		return "", fmt.Errorf("cannot translate %q: not an absolute path", linuxPath)
+	}
+
+	t, gen := r.current()
+	key := cacheKey("w", mode, linuxPath)
+	if cached, ok := r.cache.get(key); ok {
+		return cached, nil
+	}
+
+	// Clean the path to resolve . and .. components.
+	cleaned := filepath.Clean(linuxPath)
+	if mode&ResolveSymlinks != 0 {
+		cleaned = resolveSymlinks(cleaned)
+	}
+	if mode&CanonicalCase != 0 {
+		cleaned = t.canonicalCase(cleaned)
+	}
+
+	result, err := t.toWindowsPath(cleaned)
+	if err != nil {
+		return "", err
+	}
+
+	r.cache.put(key, result, gen)
+	return result, nil
+}
+
+// toWindowsPath performs the actual conversion without caching.
+func (t *table) toWindowsPath(linuxPath string) (string, error) {
+	// Mount points are ordered longest first, so nested mounts win.
+	for _, m := range t.mounts {
+		root := m.DriveLetter + ":\\"
+		if m.Share != "" {
+			root = m.Share + "\\"
+		}
+		if linuxPath == m.MountPoint {
+			// Exact match: /mnt/c → C:\, /mnt/builds → \\fileserver\builds
+			if m.Share != "" {
+				return m.Share, nil
+			}
+			return root, nil
+		}
+		prefix := strings.TrimSuffix(m.MountPoint, "/") + "/"
+		if strings.HasPrefix(linuxPath, prefix) {
+			rest := escapePath(strings.TrimPrefix(linuxPath, prefix), "/")
+			winRest := strings.ReplaceAll(rest, "/", "\\")
+			return root + winRest, nil
+		}
+	}
+
+	// Not a Windows drive mount — generate UNC path.
+	distro, host := t.distro, t.host
+	if distro == "" && t.discover {
+		var err error
+		if distro, err = DistroName(); err != nil {
+			return "", fmt.Errorf("cannot translate %q: %w", linuxPath, err)
+		}
+	}
+	if distro == "" {
+		return "", fmt.Errorf("cannot translate %q: not under a drive mount and no distro name is set", linuxPath)
+	}
+	if host == "" && t.discover {
+		host = DetectHost()
+	}
+	winPath := strings.ReplaceAll(escapePath(linuxPath, "/"), "/", "\\")
+	return `\\` + host + `\` + distro + winPath, nil
+}
+
+// ToLinuxPath translates a Windows path to a Linux path.
+//
+// Algorithm:
+//  1. "X:\..." → "<mount point of X>/...", by default "/mnt/x/..."
+//  2. "\\wsl.localhost\<distro>\..." → "/..."
+//  3. "\\server\share\..." → "<mount point of the share>/...", or an
+//     error if the share is not mounted
+//
+// Long-path (\\?\C:\..., \\?\UNC\...) and device (\\.\C:\...) forms and
+// forward slashes (C:/Users) are accepted too. Characters escaped by
+// drvfs are unescaped; see UnescapeName.
+//
+// Results are memoized.
+func (r *Resolver) ToLinuxPath(windowsPath string) (string, error) {
+	return r.ToLinuxPathMode(windowsPath, 0)
+}
+
+// ToLinuxPathMode is ToLinuxPath with the optional steps of mode; only
+// CanonicalCase applies. Results are memoized per mode.
+func (r *Resolver) ToLinuxPathMode(windowsPath string, mode Mode) (string, error) {
+	if windowsPath == "" {
+		return "", fmt.Errorf("empty path provided")
+	}
+
+	t, gen := r.current()
+	key := cacheKey("u", mode, windowsPath)
+	if cached, ok := r.cache.get(key); ok {
+		return cached, nil
+	}
+
+	result, err := t.toLinuxPath(windowsPath)
+	if err != nil {
+		return "", err
+	}
+	result = UnescapeName(result)
+	if mode&CanonicalCase != 0 {
+		result = t.canonicalCase(result)
+	}
+
+	r.cache.put(key, result, gen)
+	return result, nil
+}
+
+// toLinuxPath performs the actual conversion without caching.
+func (t *table) toLinuxPath(windowsPath string) (string, error) {
+	windowsPath, err := normalizeWindowsPath(windowsPath)
+	if err != nil {
+		return "", err
+	}
+
+	// Handle UNC paths: \\wsl.localhost\distro\path or \\wsl$\distro\path
+	if strings.HasPrefix(windowsPath, `\\wsl.localhost\`) || strings.HasPrefix(windowsPath, `\\wsl$\`) {
+		var rest string
+		if strings.HasPrefix(windowsPath, `\\wsl.localhost\`) {
+			rest = strings.TrimPrefix(windowsPath, `\\wsl.localhost\`)
+		} else {
+			rest = strings.TrimPrefix(windowsPath, `\\wsl$\`)
+		}
+		// Skip distro name.
+		idx := strings.Index(rest, `\`)
+		if idx >= 0 {
+			linuxPath := rest[idx:]
+			linuxPath = strings.ReplaceAll(linuxPath, `\`, "/")
+			return filepath.Clean(linuxPath), nil
+		}
+		return "/", nil
+	}
+
+	// Handle network shares: \\server\share\... → where the share is mounted.
+	if strings.HasPrefix(windowsPath, `\\`) {
+		return t.shareLinuxPath(windowsPath)
+	}
+
+	// Handle drive letter paths: C:\Users\... → /mnt/c/Users/..., or
+	// wherever the drive is mounted.
+	if len(windowsPath) >= 2 && windowsPath[1] == ':' && unicode.IsLetter(rune(windowsPath[0])) {
+		mountPoint, err := t.drivePath(windowsPath[:1])
+		if err != nil {
+			return "", err
+		}
+		rest := ""
+		if len(windowsPath) > 2 {
+			rest = windowsPath[2:]
+			if strings.HasPrefix(rest, `\`) {
+				rest = rest[1:]
+			}
+			rest = strings.ReplaceAll(rest, `\`, "/")
+		}
+		if rest == "" {
+			return mountPoint, nil
+		}
+		return filepath.Clean(mountPoint + "/" + rest), nil
+	}
+
+	return "", fmt.Errorf("unrecognized Windows path format: %q", windowsPath)
+}
+
+// drivePath returns the Linux path a drive letter is mounted at: its entry
+// in the mount table, or where automount would put it. With automount
+// disabled, a drive missing from the mount table is an error.
+func (t *table) drivePath(letter string) (string, error) {
+	for _, m := range t.mounts {
+		if strings.EqualFold(m.DriveLetter, letter) {
+			return m.MountPoint, nil
+		}
+	}
+	if !t.automount {
+		drive := strings.ToUpper(letter) + ":"
+		return "", fmt.Errorf("drive %s is not mounted in WSL and automount is disabled (mount it with: sudo mount -t drvfs %s <dir>)", drive, drive)
+	}
+	return t.root + strings.ToLower(letter), nil
+}
+
+// shareLinuxPath translates a path on a network share through the mount
+// of the share, or of the longest part of it that is mounted.
+func (t *table) shareLinuxPath(windowsPath string) (string, error) {
+	if uncShare(windowsPath) == "" {
+		return "", fmt.Errorf("unrecognized Windows path format: %q", windowsPath)
+	}
+
+	var best Mount
+	for _, m := range t.mounts {
+		if m.Share != "" && len(m.Share) > len(best.Share) && hasPathPrefixFold(windowsPath, m.Share) {
+			best = m
+		}
+	}
+	if best.Share == "" {
+		host, rest, _ := strings.Cut(windowsPath[2:], `\`)
+		name, _, _ := strings.Cut(rest, `\`)
+		share := `\\` + host + `\` + name
+		return "", fmt.Errorf("network share %s is not mounted in WSL (mount it with: sudo mount -t drvfs '%s' <dir>)", share, share)
+	}
+	rest := strings.TrimLeft(windowsPath[len(best.Share):], `\`)
+	if rest == "" {
+		return best.MountPoint, nil
+	}
+	return filepath.Clean(best.MountPoint + "/" + strings.ReplaceAll(rest, `\`, "/")), nil
+}
+
+// canonicalCase returns p with each component below a drive or share
+// mount replaced by the directory entry that matches it ignoring case.
+// An exact match is preferred, and matching stops at the first component
+// with no entry.
+func (t *table) canonicalCase(p string) string {
+	var mount string
+	for _, m := range t.mounts {
+		if p == m.MountPoint || strings.HasPrefix(p, strings.TrimSuffix(m.MountPoint, "/")+"/") {
+			mount = m.MountPoint
+			break
+		}
+	}
+	if mount == "" || p == mount {
+		return p
+	}
+
+	dir := mount
+	parts := strings.Split(strings.TrimPrefix(p[len(mount):], "/"), "/")
+	for i, part := range parts {
+		name, ok := matchEntry(dir, part)
+		if !ok {
+			return filepath.Join(append([]string{dir}, parts[i:]...)...)
+		}
+		dir = filepath.Join(dir, name)
+	}
+	return dir
+}
+
+// ClearCache clears the memoized translations. The counters reported by
+// CacheStats are kept.
+func (r *Resolver) ClearCache() {
+	r.cache.invalidate()
+}
+
+// CacheStats returns the counters of the translation cache.
+func (r *Resolver) CacheStats() CacheStats {
+	return r.cache.stats()
+}
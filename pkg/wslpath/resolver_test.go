@@ -0,0 +1,111 @@
+package wslpath
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestResolver(t *testing.T) {
+	r := New(Config{
+		Mounts: []Mount{
+			{DriveLetter: "C", MountPoint: "/c"},
+			{Share: `\\fileserver\builds`, MountPoint: "/c/builds"},
+		},
+		AutomountRoot: "/drives",
+		Distro:        "Debian",
+		Host:          HostLegacy,
+	})
+
+	toWin := map[string]string{
+		"/c/Users/me":       `C:\Users\me`,
+		"/c/builds/app.zip": `\\fileserver\builds\app.zip`,
+		"/home/me":          `\\wsl$\Debian\home\me`,
+	}
+	for in, want := range toWin {
+		if got, err := r.ToWindowsPath(in); err != nil || got != want {
+			t.Errorf("ToWindowsPath(%q) = %q, %v; want %q", in, got, err, want)
+		}
+	}
+
+	toLinux := map[string]string{
+		`C:\Users\me`:                     "/c/Users/me",
+		`\\fileserver\builds\app.zip`:     "/c/builds/app.zip",
+		`D:\data`:                         "/drives/d/data",
+		`\\wsl.localhost\Debian\home\me`:  "/home/me",
+		`\\wsl$\Debian\home\me\notes.txt`: "/home/me/notes.txt",
+	}
+	for in, want := range toLinux {
+		if got, err := r.ToLinuxPath(in); err != nil || got != want {
+			t.Errorf("ToLinuxPath(%q) = %q, %v; want %q", in, got, err, want)
+		}
+	}
+}
+
+func TestResolver_Independent(t *testing.T) {
+	setupMockMounts(t)
+	a := New(Config{Distro: "Alpine"})
+	b := New(Config{Mounts: []Mount{{DriveLetter: "C", MountPoint: "/mnt/c"}}, Distro: "Arch"})
+
+	if got, _ := a.ToWindowsPath("/mnt/c/x"); got != `\\wsl.localhost\Alpine\mnt\c\x` {
+		t.Errorf("a: got %q", got)
+	}
+	if got, _ := b.ToWindowsPath("/mnt/c/x"); got != `C:\x` {
+		t.Errorf("b: got %q", got)
+	}
+	if got, _ := ToWindowsPath("/home"); got != `\\wsl.localhost\Ubuntu\home` {
+		t.Errorf("default: got %q", got)
+	}
+	if s := a.CacheStats(); s.Misses != 1 || s.Entries != 1 {
+		t.Errorf("a stats = %+v, want its own cache", s)
+	}
+}
+
+func TestResolver_NoDistro(t *testing.T) {
+	r := New(Config{Mounts: []Mount{{DriveLetter: "C", MountPoint: "/mnt/c"}}})
+	if got, err := r.ToWindowsPath("/mnt/c/x"); err != nil || got != `C:\x` {
+		t.Errorf("drive path: got %q, %v", got, err)
+	}
+	if _, err := r.ToWindowsPath("/home/me"); err == nil || !strings.Contains(err.Error(), "no distro name") {
+		t.Errorf("UNC path without distro: err = %v", err)
+	}
+}
+
+func TestResolver_RelativePath(t *testing.T) {
+	r := New(Config{Mounts: []Mount{{DriveLetter: "C", MountPoint: "/mnt/c"}}, Distro: "Ubuntu"})
+	for _, in := range []string{"rel/x", "../x", "./mnt/c/x"} {
+		if got, err := r.ToWindowsPath(in); err == nil || !strings.Contains(err.Error(), "not an absolute path") {
+			t.Errorf("ToWindowsPath(%q) = %q, %v; want a not-absolute error", in, got, err)
+		}
+	}
+}
+
+func TestResolver_CacheSize(t *testing.T) {
+	r := New(Config{Distro: "Ubuntu", CacheSize: -1})
+	r.ToWindowsPath("/a")
+	r.ToWindowsPath("/a")
+	if s := r.CacheStats(); s.Entries != 0 || s.Hits != 0 {
+		t.Errorf("disabled cache stats = %+v", s)
+	}
+	if s := New(Config{}).CacheStats(); s.Capacity != DefaultPathCacheSize {
+		t.Errorf("default capacity = %d", s.Capacity)
+	}
+}
+
+func TestParseConfig(t *testing.T) {
+	cfg := ParseConfig(customRootMounts, "[automount]\nroot = /\n")
+	if cfg.AutomountRoot != "/" || len(cfg.Mounts) != 3 {
+		t.Fatalf("ParseConfig = %+v", cfg)
+	}
+	cfg.Distro = "Ubuntu"
+	if got, _ := New(cfg).ToLinuxPath(`F:\x`); got != "/f/x" {
+		t.Errorf("unmounted drive: got %q, want /f/x", got)
+	}
+
+	cfg = ParseConfig(customRootMounts, "[automount]\nenabled = false\n")
+	if !cfg.NoAutomount {
+		t.Fatalf("ParseConfig with enabled = false: NoAutomount not set")
+	}
+	if _, err := New(cfg).ToLinuxPath(`F:\x`); err == nil {
+		t.Error("unmounted drive with automount disabled: want an error")
+	}
+}